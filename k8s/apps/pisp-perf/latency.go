@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	icmpProtoICMP   = 1  // IPPROTO_ICMP
+	icmpProtoICMPv6 = 58 // IPPROTO_ICMPv6
+)
+
+// LatencyStats summarizes a set of round-trip times measured by pingRTTs.
+type LatencyStats struct {
+	MinMs float64 `json:"min_ms"`
+	AvgMs float64 `json:"avg_ms"`
+	P50Ms float64 `json:"p50_ms"`
+	P99Ms float64 `json:"p99_ms"`
+	MaxMs float64 `json:"max_ms"`
+	Sent  int     `json:"sent"`
+	Recv  int     `json:"received"`
+}
+
+// pingRTTs sends count ICMP echo requests to host and returns every RTT
+// it heard a reply for within timeout. It prefers a raw ICMP socket,
+// falling back to the unprivileged datagram-ICMP mode Linux exposes to
+// non-root users (net.ipv4.ping_group_range) so the tool still works
+// without CAP_NET_RAW.
+func pingRTTs(host string, count int, timeout time.Duration) ([]time.Duration, error) {
+	ipAddr, err := net.ResolveIPAddr("ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", host, err)
+	}
+	isV6 := ipAddr.IP.To4() == nil
+
+	rawNetwork, unprivNetwork := "ip4:icmp", "udp4"
+	msgType := icmp.Type(ipv4.ICMPTypeEcho)
+	proto := icmpProtoICMP
+	if isV6 {
+		rawNetwork, unprivNetwork = "ip6:ipv6-icmp", "udp6"
+		msgType = ipv6.ICMPTypeEchoRequest
+		proto = icmpProtoICMPv6
+	}
+
+	var dst net.Addr = ipAddr
+	conn, err := icmp.ListenPacket(rawNetwork, "0.0.0.0")
+	if err != nil {
+		conn, err = icmp.ListenPacket(unprivNetwork, "0.0.0.0")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ICMP socket (tried %s and %s): %v", rawNetwork, unprivNetwork, err)
+		}
+		dst = &net.UDPAddr{IP: ipAddr.IP}
+	}
+	defer conn.Close()
+
+	id := os.Getpid() & 0xffff
+
+	// Send every echo request back-to-back; replies are matched by the
+	// send timestamp embedded in each payload rather than arrival
+	// order, so a reordered reply is still attributed the right RTT.
+	for seq := 1; seq <= count; seq++ {
+		payload := make([]byte, 8)
+		binary.BigEndian.PutUint64(payload, uint64(time.Now().UnixNano()))
+
+		wb, err := (&icmp.Message{
+			Type: msgType,
+			Code: 0,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: payload},
+		}).Marshal(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal ICMP echo: %v", err)
+		}
+		if _, err := conn.WriteTo(wb, dst); err != nil {
+			return nil, fmt.Errorf("failed to send ICMP echo: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	rtts := make([]time.Duration, 0, count)
+	buf := make([]byte, 1500)
+
+	for len(rtts) < count {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return rtts, nil
+		}
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+
+		reply, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.ID != id || len(echo.Data) < 8 {
+			continue
+		}
+
+		sentNs := int64(binary.BigEndian.Uint64(echo.Data[:8]))
+		rtts = append(rtts, time.Since(time.Unix(0, sentNs)))
+	}
+
+	return rtts, nil
+}
+
+// computeLatencyStats turns a batch of RTTs into min/avg/max/p50/p99
+// summary statistics, in milliseconds.
+func computeLatencyStats(rtts []time.Duration, sent int) LatencyStats {
+	stats := LatencyStats{Sent: sent, Recv: len(rtts)}
+	if len(rtts) == 0 {
+		return stats
+	}
+
+	sorted := make([]time.Duration, len(rtts))
+	copy(sorted, rtts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+
+	stats.MinMs = sorted[0].Seconds() * 1000
+	stats.MaxMs = sorted[len(sorted)-1].Seconds() * 1000
+	stats.AvgMs = (sum.Seconds() * 1000) / float64(len(sorted))
+	stats.P50Ms = percentile(sorted, 50).Seconds() * 1000
+	stats.P99Ms = percentile(sorted, 99).Seconds() * 1000
+	return stats
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	idx := (len(sorted) * p) / 100
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
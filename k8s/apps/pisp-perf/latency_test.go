@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeLatencyStats(t *testing.T) {
+	rtts := []time.Duration{
+		50 * time.Millisecond,
+		10 * time.Millisecond,
+		30 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+	}
+
+	stats := computeLatencyStats(rtts, 10)
+
+	if stats.Sent != 10 {
+		t.Errorf("Sent = %d, want 10", stats.Sent)
+	}
+	if stats.Recv != len(rtts) {
+		t.Errorf("Recv = %d, want %d", stats.Recv, len(rtts))
+	}
+	if stats.MinMs != 10 {
+		t.Errorf("MinMs = %v, want 10", stats.MinMs)
+	}
+	if stats.MaxMs != 50 {
+		t.Errorf("MaxMs = %v, want 50", stats.MaxMs)
+	}
+	if stats.AvgMs != 30 {
+		t.Errorf("AvgMs = %v, want 30", stats.AvgMs)
+	}
+}
+
+func TestComputeLatencyStatsNoReplies(t *testing.T) {
+	stats := computeLatencyStats(nil, 5)
+
+	if stats.Sent != 5 || stats.Recv != 0 {
+		t.Errorf("got Sent=%d Recv=%d, want Sent=5 Recv=0", stats.Sent, stats.Recv)
+	}
+	if stats.MinMs != 0 || stats.MaxMs != 0 || stats.AvgMs != 0 {
+		t.Errorf("expected zero-valued stats with no replies, got %+v", stats)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		30 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+	}
+
+	if got := percentile(sorted, 0); got != 10*time.Millisecond {
+		t.Errorf("p0 = %v, want 10ms", got)
+	}
+	if got := percentile(sorted, 99); got != 50*time.Millisecond {
+		t.Errorf("p99 = %v, want 50ms", got)
+	}
+
+	// A single element should never index out of range regardless of p.
+	if got := percentile([]time.Duration{5 * time.Millisecond}, 99); got != 5*time.Millisecond {
+		t.Errorf("single-element percentile = %v, want 5ms", got)
+	}
+}
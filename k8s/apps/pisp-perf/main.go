@@ -3,18 +3,25 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/pion/dtls/v2"
 )
 
 type ControlMessage struct {
@@ -24,27 +31,44 @@ type ControlMessage struct {
 }
 
 type BandwidthStats struct {
-	BytesReceived int64   `json:"bytes_received"`
-	PacketsReceived int64 `json:"packets_received"`
-	Duration      float64 `json:"duration"`
-	BandwidthMbps float64 `json:"bandwidth_mbps"`
-	StartTime     time.Time
-	LastPacketTime time.Time
+	BytesReceived     int64   `json:"bytes_received"`
+	PacketsReceived   int64   `json:"packets_received"`
+	Duration          float64 `json:"duration"`
+	BandwidthMbps     float64 `json:"bandwidth_mbps"`
+	HighestSeq        uint64  `json:"highest_seq"`
+	PacketsLost       int64   `json:"packets_lost"`
+	PacketsReordered  int64   `json:"packets_reordered"`
+	PacketsDuplicated int64   `json:"packets_duplicated"`
+	JitterMs          float64 `json:"jitter_ms"`
+	StartTime         time.Time
+	LastPacketTime    time.Time
+
+	seenAny       bool
+	seenWindow    [udpSeqWindowWords]uint64
+	haveTransit   bool
+	lastTransitNs int64
+	jitterNs      float64
 }
 
 type Server struct {
-	controlPort int
-	dataPort    int
-	
-	tcpListener net.Listener
-	udpConn     *net.UDPConn
-	
+	controlPort  int
+	dataPort     int
+	external     bool
+	security     SecurityOptions
+	outputFormat string
+
+	tcpListener   net.Listener
+	udpConn       *net.UDPConn
+	dtlsListener  net.Listener
+	tcpBWListener net.Listener
+	cpsListener   net.Listener
+	httpServer    *http.Server
+	httpsServer   *http.Server
+
 	clients     map[string]*ClientSession
+	tokens      map[string]*ClientSession
 	clientsMux  sync.RWMutex
-	
-	stats       *BandwidthStats
-	statsMux    sync.RWMutex
-	
+
 	ctx         context.Context
 	cancel      context.CancelFunc
 }
@@ -53,73 +77,145 @@ type ClientSession struct {
 	ID       string
 	TCPConn  net.Conn
 	UDPAddr  *net.UDPAddr
+	Kind     TestKind
+	Token    string
 	LastSeen time.Time
 	Testing  bool
+
+	// Stats/Flows/StatsMu are scoped to this session so concurrent clients
+	// don't clobber each other's in-flight counters when one of them sends
+	// START_TEST.
+	Stats   *BandwidthStats
+	Flows   map[string]*BandwidthStats
+	StatsMu sync.Mutex
 }
 
 type Client struct {
-	host        string
+	controlHost string
+	dataHost    string
 	controlPort int
 	dataPort    int
-	
-	tcpConn     net.Conn
-	udpConn     *net.UDPConn
-	serverAddr  *net.UDPAddr
-	
+	latencyOnly bool
+	kind        TestKind
+	parallel    int
+	targetMbps  float64
+	external    bool
+	security    SecurityOptions
+
+	tcpConn            net.Conn
+	tcpReader          *bufio.Reader
+	udpConn            *net.UDPConn
+	serverAddr         *net.UDPAddr
+	sessionToken       []byte
+	controlHandshakeMs float64
+
 	ctx         context.Context
 	cancel      context.CancelFunc
 }
 
 type TestResult struct {
-	PayloadSize   int
-	BandwidthMbps float64
-	LatencyMs     float64
-	BytesSent     int64
-	Duration      time.Duration
+	PayloadSize       int
+	BandwidthMbps     float64
+	ConnectionsPerSec float64
+	Latency           LatencyStats
+	BytesSent         int64
+	Duration          time.Duration
+	HandshakeMs       float64
+	LossPct           float64
+	JitterMs          float64
 }
 
-func NewServer(controlPort, dataPort int) *Server {
+func NewServer(controlPort, dataPort int, external bool, security SecurityOptions, outputFormat string) *Server {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
-		controlPort: controlPort,
-		dataPort:    dataPort,
-		clients:     make(map[string]*ClientSession),
-		stats:       &BandwidthStats{},
-		ctx:         ctx,
-		cancel:      cancel,
+		controlPort:  controlPort,
+		dataPort:     dataPort,
+		external:     external,
+		security:     security,
+		outputFormat: outputFormat,
+		clients:      make(map[string]*ClientSession),
+		tokens:       make(map[string]*ClientSession),
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 }
 
 func (s *Server) Start() error {
 	log.Printf("Starting PISP Performance Server on control port %d, data port %d", s.controlPort, s.dataPort)
-	
+
 	// Start TCP control server
-	tcpAddr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", s.controlPort))
-	if err != nil {
-		return fmt.Errorf("failed to resolve TCP address: %w", err)
-	}
-	
-	s.tcpListener, err = net.ListenTCP("tcp", tcpAddr)
-	if err != nil {
-		return fmt.Errorf("failed to listen on TCP port %d: %w", s.controlPort, err)
+	var err error
+	if s.security.TLS {
+		tlsConfig, cfgErr := s.security.serverTLSConfig("0.0.0.0")
+		if cfgErr != nil {
+			return fmt.Errorf("failed to build control channel TLS config: %w", cfgErr)
+		}
+		s.tcpListener, err = tls.Listen("tcp", fmt.Sprintf(":%d", s.controlPort), tlsConfig)
+		if err != nil {
+			return fmt.Errorf("failed to listen on TLS control port %d: %w", s.controlPort, err)
+		}
+	} else {
+		tcpAddr, resolveErr := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", s.controlPort))
+		if resolveErr != nil {
+			return fmt.Errorf("failed to resolve TCP address: %w", resolveErr)
+		}
+		s.tcpListener, err = net.ListenTCP("tcp", tcpAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on TCP port %d: %w", s.controlPort, err)
+		}
 	}
-	
+
 	// Start UDP data server
 	udpAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf(":%d", s.dataPort))
 	if err != nil {
 		return fmt.Errorf("failed to resolve UDP address: %w", err)
 	}
-	
-	s.udpConn, err = net.ListenUDP("udp", udpAddr)
+
+	if s.security.DTLS {
+		dtlsConfig, cfgErr := s.security.serverDTLSConfig("0.0.0.0")
+		if cfgErr != nil {
+			return fmt.Errorf("failed to build data channel DTLS config: %w", cfgErr)
+		}
+		s.dtlsListener, err = dtls.Listen("udp", udpAddr, dtlsConfig)
+		if err != nil {
+			return fmt.Errorf("failed to listen on DTLS data port %d: %w", s.dataPort, err)
+		}
+	} else {
+		s.udpConn, err = net.ListenUDP("udp", udpAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on UDP port %d: %w", s.dataPort, err)
+		}
+	}
+
+	// Start the protocol-specific listeners the multi-protocol test
+	// suite needs alongside the UDP bandwidth port.
+	tcpBWAddr := fmt.Sprintf(":%d", s.dataPort+tcpBandwidthPortOffset)
+	s.tcpBWListener, err = net.Listen("tcp", tcpBWAddr)
 	if err != nil {
-		return fmt.Errorf("failed to listen on UDP port %d: %w", s.dataPort, err)
+		return fmt.Errorf("failed to listen for TCP bandwidth on %s: %w", tcpBWAddr, err)
 	}
-	
+
+	cpsAddr := fmt.Sprintf(":%d", s.dataPort+tcpCPSPortOffset)
+	s.cpsListener, err = net.Listen("tcp", cpsAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for TCP CPS on %s: %w", cpsAddr, err)
+	}
+
+	if err := s.startHTTPBandwidthServers("0.0.0.0"); err != nil {
+		return err
+	}
+
 	// Start background routines
 	go s.acceptTCPConnections()
-	go s.handleUDPData()
+	if s.security.DTLS {
+		go s.acceptDTLSConnections()
+	} else {
+		go s.handleUDPData()
+	}
+	go s.acceptTCPBandwidthConnections()
+	go s.acceptTCPCPSConnections()
 	go s.cleanupStaleClients()
-	
+
 	log.Println("PISP Performance Server started successfully")
 	return nil
 }
@@ -136,7 +232,23 @@ func (s *Server) Stop() error {
 	if s.udpConn != nil {
 		s.udpConn.Close()
 	}
-	
+	if s.dtlsListener != nil {
+		s.dtlsListener.Close()
+	}
+
+	if s.tcpBWListener != nil {
+		s.tcpBWListener.Close()
+	}
+	if s.cpsListener != nil {
+		s.cpsListener.Close()
+	}
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+	if s.httpsServer != nil {
+		s.httpsServer.Close()
+	}
+
 	// Close all client connections
 	s.clientsMux.Lock()
 	for _, client := range s.clients {
@@ -181,6 +293,8 @@ func (s *Server) handleTCPClient(conn net.Conn) {
 		TCPConn:  conn,
 		LastSeen: time.Now(),
 		Testing:  false,
+		Stats:    &BandwidthStats{},
+		Flows:    make(map[string]*BandwidthStats),
 	}
 	
 	s.clientsMux.Lock()
@@ -190,6 +304,9 @@ func (s *Server) handleTCPClient(conn net.Conn) {
 	defer func() {
 		s.clientsMux.Lock()
 		delete(s.clients, clientID)
+		if client.Token != "" {
+			delete(s.tokens, client.Token)
+		}
 		s.clientsMux.Unlock()
 		log.Printf("TCP client disconnected: %s", clientID)
 	}()
@@ -249,13 +366,15 @@ func (s *Server) handleStartTest(client *ClientSession, data string) {
 	log.Printf("Starting bandwidth test for client %s", client.ID)
 	
 	client.Testing = true
-	
-	// Reset stats
-	s.statsMux.Lock()
-	s.stats = &BandwidthStats{
+
+	// Reset this client's stats. Scoped to the session (not the server) so
+	// one client's START_TEST can't wipe another client's in-flight test.
+	client.StatsMu.Lock()
+	client.Stats = &BandwidthStats{
 		StartTime: time.Now(),
 	}
-	s.statsMux.Unlock()
+	client.Flows = make(map[string]*BandwidthStats)
+	client.StatsMu.Unlock()
 	
 	// Parse test parameters
 	params := make(map[string]string)
@@ -277,34 +396,101 @@ func (s *Server) handleStartTest(client *ClientSession, data string) {
 	if ps, ok := params["packetSize"]; ok {
 		packetSize = ps
 	}
-	
-	log.Printf("Test parameters - Duration: %ss, Packet Size: %s bytes", duration, packetSize)
-	
-	s.sendControlMessage(client, "TEST_READY", fmt.Sprintf("duration:%s,packetSize:%s", duration, packetSize))
+
+	client.Kind = TestUDPBandwidth
+	if k, ok := params["kind"]; ok && k != "" {
+		client.Kind = TestKind(k)
+	}
+
+	token := ""
+	if s.external {
+		var err error
+		token, err = s.issueSessionToken(client)
+		if err != nil {
+			log.Printf("Failed to issue session token for %s: %v", client.ID, err)
+		}
+	}
+
+	log.Printf("Test parameters - Duration: %ss, Packet Size: %s bytes, Kind: %s", duration, packetSize, client.Kind)
+
+	s.sendControlMessage(client, "TEST_READY", fmt.Sprintf("duration:%s,packetSize:%s,kind:%s,token:%s", duration, packetSize, client.Kind, token))
+}
+
+// issueSessionToken generates a random 128-bit token for client and
+// registers it so external-mode UDP traffic can be attributed to this
+// session by token instead of by source address, which a NAT or L4 load
+// balancer in front of the server may rewrite or share across clients.
+func (s *Server) issueSessionToken(client *ClientSession) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	s.clientsMux.Lock()
+	if client.Token != "" {
+		delete(s.tokens, client.Token)
+	}
+	client.Token = token
+	s.tokens[token] = client
+	s.clientsMux.Unlock()
+
+	return token, nil
 }
 
 func (s *Server) handleStopTest(client *ClientSession, data string) {
 	log.Printf("Stopping bandwidth test for client %s", client.ID)
 	
 	client.Testing = false
-	
-	s.statsMux.Lock()
-	stats := *s.stats
+
+	client.StatsMu.Lock()
+	stats := *client.Stats
 	if !stats.StartTime.IsZero() && !stats.LastPacketTime.IsZero() {
 		stats.Duration = stats.LastPacketTime.Sub(stats.StartTime).Seconds()
 		if stats.Duration > 0 {
 			stats.BandwidthMbps = float64(stats.BytesReceived*8) / (stats.Duration * 1000000)
 		}
 	}
-	s.statsMux.Unlock()
-	
-	resultsData := fmt.Sprintf("bytes_received:%d,packets_received:%d,duration:%.2f,bandwidth_mbps:%.2f",
-		stats.BytesReceived, stats.PacketsReceived, stats.Duration, stats.BandwidthMbps)
-	
+	flows := make(map[string]*BandwidthStats, len(client.Flows))
+	for flowKey, flowStats := range client.Flows {
+		flowCopy := *flowStats
+		flows[flowKey] = &flowCopy
+	}
+	client.StatsMu.Unlock()
+
+	var lost, reordered, duplicated int64
+	var jitterSum float64
+	for _, flowStats := range flows {
+		lost += flowStats.PacketsLost
+		reordered += flowStats.PacketsReordered
+		duplicated += flowStats.PacketsDuplicated
+		jitterSum += flowStats.JitterMs
+	}
+	jitterMs := 0.0
+	if len(flows) > 0 {
+		jitterMs = jitterSum / float64(len(flows))
+	}
+
+	resultsData := fmt.Sprintf("bytes_received:%d,packets_received:%d,duration:%.2f,bandwidth_mbps:%.2f,streams:%d,packets_lost:%d,packets_reordered:%d,packets_duplicated:%d,jitter_ms:%.3f,flow_data:%s",
+		stats.BytesReceived, stats.PacketsReceived, stats.Duration, stats.BandwidthMbps, len(flows), lost, reordered, duplicated, jitterMs, flowSummary(flows))
+
 	s.sendControlMessage(client, "TEST_RESULTS", resultsData)
-	
-	log.Printf("Test completed - Received %d bytes (%d packets) in %.2fs, Bandwidth: %.2f Mbps",
-		stats.BytesReceived, stats.PacketsReceived, stats.Duration, stats.BandwidthMbps)
+
+	log.Printf("Test completed - Received %d bytes (%d packets) across %d stream(s) in %.2fs, Bandwidth: %.2f Mbps, Lost: %d, Reordered: %d, Duplicated: %d, Jitter: %.3f ms",
+		stats.BytesReceived, stats.PacketsReceived, len(flows), stats.Duration, stats.BandwidthMbps, lost, reordered, duplicated, jitterMs)
+}
+
+// flowSummary renders each flow's counters as a single "|"-separated
+// string so it fits in the flat key:value control-message wire format
+// alongside the aggregate totals.
+func flowSummary(flows map[string]*BandwidthStats) string {
+	parts := make([]string, 0, len(flows))
+	for flowKey, flowStats := range flows {
+		parts = append(parts, fmt.Sprintf("%s=bytes:%d;packets:%d;lost:%d;reordered:%d;duplicated:%d;jitter_ms:%.3f",
+			flowKey, flowStats.BytesReceived, flowStats.PacketsReceived,
+			flowStats.PacketsLost, flowStats.PacketsReordered, flowStats.PacketsDuplicated, flowStats.JitterMs))
+	}
+	return strings.Join(parts, "|")
 }
 
 func (s *Server) sendControlMessage(client *ClientSession, command, data string) {
@@ -352,41 +538,204 @@ func (s *Server) handleUDPData() {
 	}
 }
 
+// acceptDTLSConnections is the DTLS analogue of handleUDPData: since a
+// DTLS session is connection-oriented, each peer gets its own accepted
+// net.Conn (handshake already completed by the time Accept returns)
+// instead of every datagram arriving on one shared socket.
+func (s *Server) acceptDTLSConnections() {
+	for {
+		conn, err := s.dtlsListener.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to accept DTLS connection: %v", err)
+			continue
+		}
+		go s.handleDTLSConn(conn)
+	}
+}
+
+// handleDTLSConn reads decrypted datagrams off a single DTLS session and
+// feeds them into the same processUDPPacket path the cleartext UDP
+// socket uses, so loss/reorder/jitter tracking and external-mode token
+// attribution behave identically either way.
+func (s *Server) handleDTLSConn(conn net.Conn) {
+	defer conn.Close()
+
+	addr, ok := conn.RemoteAddr().(*net.UDPAddr)
+	if !ok {
+		var err error
+		addr, err = net.ResolveUDPAddr("udp", conn.RemoteAddr().String())
+		if err != nil {
+			log.Printf("Failed to resolve DTLS peer address %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+
+	buffer := make([]byte, 65536)
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		default:
+		}
+
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, err := conn.Read(buffer)
+		if n > 0 {
+			s.processUDPPacket(addr, buffer[:n])
+		}
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			if s.ctx.Err() != nil {
+				return
+			}
+			return
+		}
+	}
+}
+
 func (s *Server) processUDPPacket(addr *net.UDPAddr, data []byte) {
+	if s.external {
+		s.processExternalUDPPacket(addr, data)
+		return
+	}
+
 	// Update client UDP address if we have a corresponding TCP client
 	clientID := fmt.Sprintf("%s:%d", addr.IP.String(), addr.Port-1) // Assume control port is data port - 1
-	
+
 	s.clientsMux.RLock()
 	client, exists := s.clients[clientID]
 	s.clientsMux.RUnlock()
-	
-	if exists {
-		client.UDPAddr = addr
-		client.LastSeen = time.Now()
-		
-		if client.Testing {
-			s.updateStats(len(data))
+
+	if !exists {
+		// No session to attribute this datagram to.
+		return
+	}
+
+	client.UDPAddr = addr
+	client.LastSeen = time.Now()
+
+	if client.Testing {
+		s.recordUDPPacket(client, addr.String(), data)
+	}
+}
+
+// processExternalUDPPacket attributes a datagram to its ClientSession by
+// the session token every external-mode packet is required to carry as
+// its first udpSessionTokenSize bytes, rather than by source address —
+// the only approach that survives a NAT or L4 load balancer sitting in
+// front of the data port. The flow key still includes the source
+// address so parallel streams from the same session are tracked
+// separately.
+func (s *Server) processExternalUDPPacket(addr *net.UDPAddr, data []byte) {
+	if len(data) < udpSessionTokenSize {
+		return
+	}
+	token := hex.EncodeToString(data[:udpSessionTokenSize])
+	payload := data[udpSessionTokenSize:]
+
+	s.clientsMux.RLock()
+	client, exists := s.tokens[token]
+	s.clientsMux.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	client.UDPAddr = addr
+	client.LastSeen = time.Now()
+
+	if client.Testing {
+		s.recordUDPPacket(client, token+"@"+addr.String(), payload)
+	}
+}
+
+// recordUDPPacket updates the aggregate and per-flow byte/packet
+// counters for a UDP datagram, and additionally feeds its sequencing
+// header (if present) into the flow's loss/reorder/jitter tracking.
+// Packets without a recognized header (legacy senders) still count
+// towards the byte/packet totals, just without sequencing stats.
+func (s *Server) recordUDPPacket(client *ClientSession, flowKey string, data []byte) {
+	client.StatsMu.Lock()
+	defer client.StatsMu.Unlock()
+
+	client.Stats.BytesReceived += int64(len(data))
+	client.Stats.PacketsReceived++
+	client.Stats.LastPacketTime = time.Now()
+
+	if !client.Stats.StartTime.IsZero() {
+		duration := time.Since(client.Stats.StartTime).Seconds()
+		if duration > 0 {
+			client.Stats.BandwidthMbps = float64(client.Stats.BytesReceived*8) / (duration * 1000000)
 		}
-	} else {
-		// Create a temporary entry for UDP-only traffic
-		s.updateStats(len(data))
+	}
+
+	flowStats, ok := client.Flows[flowKey]
+	if !ok {
+		flowStats = &BandwidthStats{StartTime: time.Now()}
+		client.Flows[flowKey] = flowStats
+	}
+	flowStats.BytesReceived += int64(len(data))
+	flowStats.PacketsReceived++
+	flowStats.LastPacketTime = time.Now()
+
+	if hdr, ok := parseUDPHeader(data); ok {
+		flowStats.recordSeq(hdr.Seq, hdr.SendTimeNs, flowStats.LastPacketTime)
 	}
 }
 
-func (s *Server) updateStats(packetSize int) {
-	s.statsMux.Lock()
-	defer s.statsMux.Unlock()
-	
-	s.stats.BytesReceived += int64(packetSize)
-	s.stats.PacketsReceived++
-	s.stats.LastPacketTime = time.Now()
-	
-	if !s.stats.StartTime.IsZero() {
-		duration := time.Since(s.stats.StartTime).Seconds()
+// updateStats records packetSize bytes against both the aggregate test
+// counters and the per-flow counters for flowKey (one of possibly many
+// parallel streams making up the test), so TEST_RESULTS can report
+// per-stream throughput alongside the total.
+func (s *Server) updateStats(client *ClientSession, flowKey string, packetSize int) {
+	client.StatsMu.Lock()
+	defer client.StatsMu.Unlock()
+
+	client.Stats.BytesReceived += int64(packetSize)
+	client.Stats.PacketsReceived++
+	client.Stats.LastPacketTime = time.Now()
+
+	if !client.Stats.StartTime.IsZero() {
+		duration := time.Since(client.Stats.StartTime).Seconds()
 		if duration > 0 {
-			s.stats.BandwidthMbps = float64(s.stats.BytesReceived*8) / (duration * 1000000)
+			client.Stats.BandwidthMbps = float64(client.Stats.BytesReceived*8) / (duration * 1000000)
 		}
 	}
+
+	flowStats, ok := client.Flows[flowKey]
+	if !ok {
+		flowStats = &BandwidthStats{StartTime: time.Now()}
+		client.Flows[flowKey] = flowStats
+	}
+	flowStats.BytesReceived += int64(packetSize)
+	flowStats.PacketsReceived++
+	flowStats.LastPacketTime = time.Now()
+}
+
+// clientByIP returns the ClientSession whose control connection shares
+// addr's IP, for data paths that don't negotiate a session of their own
+// (TCP bandwidth, CPS, and HTTP all just dial a plain port). This is the
+// same best-effort, address-based attribution the UDP data path already
+// relies on, since none of these ports carry a client-identifying token.
+func (s *Server) clientByIP(addr string) *ClientSession {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	s.clientsMux.RLock()
+	defer s.clientsMux.RUnlock()
+	for _, client := range s.clients {
+		if clientHost, _, err := net.SplitHostPort(client.ID); err == nil && clientHost == host {
+			return client
+		}
+	}
+	return nil
 }
 
 func (s *Server) cleanupStaleClients() {
@@ -422,10 +771,8 @@ func (s *Server) printStats() {
 		case <-s.ctx.Done():
 			return
 		case <-ticker.C:
-			s.statsMux.RLock()
-			stats := *s.stats
-			s.statsMux.RUnlock()
-			
+			var stats BandwidthStats
+
 			s.clientsMux.RLock()
 			clientCount := len(s.clients)
 			testingCount := 0
@@ -433,24 +780,39 @@ func (s *Server) printStats() {
 				if client.Testing {
 					testingCount++
 				}
+				client.StatsMu.Lock()
+				stats.BytesReceived += client.Stats.BytesReceived
+				stats.PacketsReceived += client.Stats.PacketsReceived
+				stats.BandwidthMbps += client.Stats.BandwidthMbps
+				client.StatsMu.Unlock()
 			}
 			s.clientsMux.RUnlock()
-			
-			if stats.BytesReceived > 0 {
-				log.Printf("Stats - Clients: %d (Testing: %d), Received: %d bytes (%d packets), Current Bandwidth: %.2f Mbps",
-					clientCount, testingCount, stats.BytesReceived, stats.PacketsReceived, stats.BandwidthMbps)
-			}
+
+			logServerStats(s.outputFormat, clientCount, testingCount, stats)
 		}
 	}
 }
 
 // Client methods
-func NewClient(host string, controlPort, dataPort int) *Client {
+func NewClient(controlHost, dataHost string, controlPort, dataPort int, latencyOnly bool, kind TestKind, parallel int, targetMbps float64, external bool, security SecurityOptions) *Client {
+	if kind == "" {
+		kind = TestUDPBandwidth
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Client{
-		host:        host,
+		controlHost: controlHost,
+		dataHost:    dataHost,
 		controlPort: controlPort,
 		dataPort:    dataPort,
+		latencyOnly: latencyOnly,
+		kind:        kind,
+		parallel:    parallel,
+		targetMbps:  targetMbps,
+		external:    external,
+		security:    security,
 		ctx:         ctx,
 		cancel:      cancel,
 	}
@@ -458,31 +820,85 @@ func NewClient(host string, controlPort, dataPort int) *Client {
 
 func (c *Client) Connect() error {
 	// Connect TCP control channel
-	tcpAddr := fmt.Sprintf("%s:%d", c.host, c.controlPort)
-	conn, err := net.Dial("tcp", tcpAddr)
+	tcpAddr := fmt.Sprintf("%s:%d", c.controlHost, c.controlPort)
+	handshakeStart := time.Now()
+
+	var conn net.Conn
+	var err error
+	if c.security.TLS {
+		tlsConfig, cfgErr := c.security.clientTLSConfig(c.controlHost)
+		if cfgErr != nil {
+			return fmt.Errorf("failed to build control channel TLS config: %v", cfgErr)
+		}
+		conn, err = tls.Dial("tcp", tcpAddr, tlsConfig)
+	} else {
+		conn, err = net.Dial("tcp", tcpAddr)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to connect to TCP control port: %v", err)
 	}
+	c.controlHandshakeMs = float64(time.Since(handshakeStart)) / float64(time.Millisecond)
 	c.tcpConn = conn
-	
+	c.tcpReader = bufio.NewReader(conn)
+
 	// Setup UDP connection
-	udpAddr := fmt.Sprintf("%s:%d", c.host, c.dataPort)
+	udpAddr := fmt.Sprintf("%s:%d", c.dataHost, c.dataPort)
 	serverAddr, err := net.ResolveUDPAddr("udp", udpAddr)
 	if err != nil {
 		c.tcpConn.Close()
 		return fmt.Errorf("failed to resolve UDP address: %v", err)
 	}
 	c.serverAddr = serverAddr
-	
+
 	udpConn, err := net.DialUDP("udp", nil, serverAddr)
 	if err != nil {
 		c.tcpConn.Close()
 		return fmt.Errorf("failed to connect to UDP data port: %v", err)
 	}
 	c.udpConn = udpConn
-	
-	// Send connect message
-	return c.sendControlMessage("CONNECT", "Go client connected")
+
+	// Send connect message and drain the server's CONNECT_ACK so it
+	// doesn't get mistaken for TEST_READY/TEST_RESULTS by the next
+	// readControlMessage call.
+	if err := c.sendControlMessage("CONNECT", "Go client connected"); err != nil {
+		return err
+	}
+	if _, err := c.readControlMessage(); err != nil {
+		return fmt.Errorf("failed to read CONNECT_ACK: %v", err)
+	}
+	return nil
+}
+
+// setSessionTokenFrom pulls the "token:<hex>" field out of a TEST_READY
+// message's data and stores the decoded bytes as c.sessionToken, so
+// every external-mode UDP datagram can be prefixed with it.
+func (c *Client) setSessionTokenFrom(data string) error {
+	for _, pair := range strings.Split(data, ",") {
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) == 2 && kv[0] == "token" {
+			raw, err := hex.DecodeString(kv[1])
+			if err != nil {
+				return fmt.Errorf("failed to decode session token: %v", err)
+			}
+			c.sessionToken = raw
+			return nil
+		}
+	}
+	return fmt.Errorf("TEST_READY response did not include a session token")
+}
+
+// readControlMessage blocks for the next newline-delimited ControlMessage
+// from the TCP control channel.
+func (c *Client) readControlMessage() (ControlMessage, error) {
+	var msg ControlMessage
+	line, err := c.tcpReader.ReadString('\n')
+	if err != nil {
+		return msg, fmt.Errorf("failed to read control message: %v", err)
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &msg); err != nil {
+		return msg, fmt.Errorf("failed to parse control message: %v", err)
+	}
+	return msg, nil
 }
 
 func (c *Client) Close() {
@@ -500,58 +916,256 @@ func (c *Client) RunBandwidthTest(payloadSize int, duration time.Duration) (Test
 	result := TestResult{
 		PayloadSize: payloadSize,
 	}
-	
+
 	// Send start test command
-	testParams := fmt.Sprintf("duration:%d,packetSize:%d", int(duration.Seconds()), payloadSize)
+	testParams := fmt.Sprintf("duration:%d,packetSize:%d,kind:%s", int(duration.Seconds()), payloadSize, c.kind)
 	if err := c.sendControlMessage("START_TEST", testParams); err != nil {
 		return result, err
 	}
-	
-	// Create test data
-	testData := make([]byte, payloadSize)
-	for i := range testData {
-		testData[i] = byte(i % 256)
+
+	// Always drain TEST_READY, even outside external mode, so it doesn't
+	// get mistaken for the TEST_RESULTS reply read after STOP_TEST below.
+	ready, err := c.readControlMessage()
+	if err != nil {
+		return result, fmt.Errorf("failed to read TEST_READY: %v", err)
 	}
-	
-	// Run test
-	startTime := time.Now()
-	endTime := startTime.Add(duration)
+	if c.external {
+		if err := c.setSessionTokenFrom(ready.Data); err != nil {
+			return result, err
+		}
+	}
+
+	pingCount := int(duration.Seconds())
+	if pingCount < 1 {
+		pingCount = 1
+	}
+	rtts, err := pingRTTs(c.dataHost, pingCount, duration)
+	if err != nil {
+		log.Printf("ICMP latency measurement failed: %v", err)
+	}
+	result.Latency = computeLatencyStats(rtts, pingCount)
+
+	if c.latencyOnly || c.kind == TestTCPLatency {
+		c.sendControlMessage("STOP_TEST", "latency_only:true")
+		c.readControlMessage() // drain TEST_RESULTS so it doesn't desync the next test
+		return result, nil
+	}
+
+	if c.kind == TestTCPCPS {
+		count, cpsDuration, err := c.runTCPCPS(duration)
+		if err != nil {
+			return result, err
+		}
+
+		result.ConnectionsPerSec = float64(count) / cpsDuration.Seconds()
+		result.Duration = cpsDuration
+		result.HandshakeMs = c.controlHandshakeMs
+
+		stopParams := fmt.Sprintf("connections:%d,duration:%.2f,cps:%.2f", count, cpsDuration.Seconds(), result.ConnectionsPerSec)
+		c.sendControlMessage("STOP_TEST", stopParams)
+		c.readControlMessage() // drain TEST_RESULTS so it doesn't desync the next test
+
+		return result, nil
+	}
+
 	var bytesSent int64
-	
-	// Send ping for latency measurement
-	pingStart := time.Now()
-	c.sendControlMessage("PING", fmt.Sprintf("%.6f", float64(pingStart.UnixNano())/1e9))
-	
-	for time.Now().Before(endTime) {
-		_, err := c.udpConn.Write(testData)
+	var actualDuration time.Duration
+	rate := newRateLimiter(c.targetMbps)
+
+	switch c.kind {
+	case TestTCPBandwidth:
+		bytesSent, actualDuration = c.runParallelTCPBandwidth(payloadSize, duration, rate)
+		result.HandshakeMs = c.controlHandshakeMs
+	case TestHTTPBandwidth, TestHTTPSBandwidth:
+		bytesSent, actualDuration, err = c.runHTTPBandwidth(duration, c.kind == TestHTTPSBandwidth)
 		if err != nil {
-			log.Printf("UDP send error: %v", err)
-			continue
+			return result, err
 		}
-		bytesSent += int64(len(testData))
-		
-		// Small delay to prevent overwhelming
-		time.Sleep(time.Millisecond)
+		result.HandshakeMs = c.controlHandshakeMs
+	default:
+		var handshakeMs float64
+		bytesSent, actualDuration, handshakeMs = c.runParallelUDPBandwidth(payloadSize, duration, rate)
+		result.HandshakeMs = handshakeMs
 	}
-	
-	actualDuration := time.Since(startTime)
-	
+
 	// Calculate bandwidth
 	bandwidthMbps := (float64(bytesSent) * 8.0) / (actualDuration.Seconds() * 1000000.0)
-	
+
 	// Send stop test command
-	stopParams := fmt.Sprintf("bytes_sent:%d,duration:%.2f,bandwidth_mbps:%.2f", 
+	stopParams := fmt.Sprintf("bytes_sent:%d,duration:%.2f,bandwidth_mbps:%.2f",
 		bytesSent, actualDuration.Seconds(), bandwidthMbps)
 	c.sendControlMessage("STOP_TEST", stopParams)
-	
+
 	result.BandwidthMbps = bandwidthMbps
 	result.BytesSent = bytesSent
 	result.Duration = actualDuration
-	result.LatencyMs = 0.0 // TODO: Implement proper latency measurement
-	
+
+	if resultsMsg, err := c.readControlMessage(); err != nil {
+		log.Printf("Failed to read TEST_RESULTS: %v", err)
+	} else {
+		lossPct, jitterMs := parseTestResultsLossAndJitter(resultsMsg.Data)
+		result.LossPct = lossPct
+		result.JitterMs = jitterMs
+	}
+
 	return result, nil
 }
 
+// parseTestResultsLossAndJitter pulls loss percentage and jitter out of a
+// TEST_RESULTS control message's flat key:value data, the same wire
+// format handleStopTest builds server-side.
+func parseTestResultsLossAndJitter(data string) (lossPct, jitterMs float64) {
+	var received, lost int64
+	for _, pair := range strings.Split(data, ",") {
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "packets_received":
+			received, _ = strconv.ParseInt(kv[1], 10, 64)
+		case "packets_lost":
+			lost, _ = strconv.ParseInt(kv[1], 10, 64)
+		case "jitter_ms":
+			jitterMs, _ = strconv.ParseFloat(kv[1], 64)
+		}
+	}
+	if total := received + lost; total > 0 {
+		lossPct = float64(lost) / float64(total) * 100
+	}
+	return lossPct, jitterMs
+}
+
+// dialUDPStream opens one data-channel connection for a parallel UDP
+// stream, wrapping it in DTLS when c.security.DTLS is set. It returns
+// the dial (and, for DTLS, handshake) latency alongside the connection
+// so callers can report handshake_ms.
+func (c *Client) dialUDPStream() (net.Conn, time.Duration, error) {
+	start := time.Now()
+	if c.security.DTLS {
+		dtlsConfig, err := c.security.clientDTLSConfig(c.dataHost)
+		if err != nil {
+			return nil, 0, err
+		}
+		conn, err := dtls.Dial("udp", c.serverAddr, dtlsConfig)
+		if err != nil {
+			return nil, 0, err
+		}
+		return conn, time.Since(start), nil
+	}
+	conn, err := net.DialUDP("udp", nil, c.serverAddr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return conn, time.Since(start), nil
+}
+
+// runParallelUDPBandwidth spins up c.parallel goroutines, each with its
+// own dialed connection, sending payloadSize-sized packets for duration.
+// A single socket hits per-connection lock contention well below line
+// rate on 10G+ links, so each stream gets its own conn. rate, if
+// non-nil, paces the aggregate send rate across every stream. The
+// handshake latency of the first stream (real for DTLS, negligible for
+// plain UDP) is returned so callers can report handshake_ms.
+func (c *Client) runParallelUDPBandwidth(payloadSize int, duration time.Duration, rate *rateLimiter) (int64, time.Duration, float64) {
+	startTime := time.Now()
+	endTime := startTime.Add(duration)
+
+	var wg sync.WaitGroup
+	var bytesSent int64
+	var handshakeMs float64
+
+	for i := 0; i < c.parallel; i++ {
+		conn, handshake, err := c.dialUDPStream()
+		if err != nil {
+			log.Printf("Failed to open parallel UDP stream %d: %v", i, err)
+			continue
+		}
+		if i == 0 {
+			handshakeMs = float64(handshake) / float64(time.Millisecond)
+		}
+
+		wg.Add(1)
+		go func(conn net.Conn, flowID uint32) {
+			defer wg.Done()
+			defer conn.Close()
+
+			// Each stream gets its own buffer. In external mode the
+			// session token leads every datagram so the server can
+			// attribute it to this test without trusting the source
+			// address; the sequence header (if there's room for it)
+			// follows so the server can track loss/reorder/jitter per
+			// flow.
+			tokenLen := 0
+			if c.external && len(c.sessionToken) == udpSessionTokenSize && payloadSize >= udpSessionTokenSize {
+				tokenLen = udpSessionTokenSize
+			}
+
+			testData := make([]byte, payloadSize)
+			for i := range testData {
+				testData[i] = byte(i % 256)
+			}
+			if tokenLen > 0 {
+				copy(testData[:tokenLen], c.sessionToken)
+			}
+
+			var seq uint64
+			for time.Now().Before(endTime) {
+				if rate != nil {
+					rate.take(len(testData))
+				}
+				if payloadSize-tokenLen >= udpHeaderSize {
+					putUDPHeader(testData[tokenLen:], flowID, seq)
+					seq++
+				}
+				n, err := conn.Write(testData)
+				if err != nil {
+					log.Printf("UDP send error: %v", err)
+					continue
+				}
+				atomic.AddInt64(&bytesSent, int64(n))
+			}
+		}(conn, udpFlowID(i))
+	}
+	wg.Wait()
+
+	return bytesSent, time.Since(startTime), handshakeMs
+}
+
+// udpFlowID derives a flow identifier for parallel UDP stream index
+// from this process's PID, so concurrent pisp-perf client runs against
+// the same server don't collide on the wire-level flow ID.
+func udpFlowID(stream int) uint32 {
+	return uint32(os.Getpid()&0xffff)<<16 | uint32(stream&0xffff)
+}
+
+// runParallelTCPBandwidth runs c.parallel concurrent runTCPBandwidth
+// streams, each over its own dialed connection, and sums their bytes
+// sent. rate, if non-nil, paces the aggregate send rate across every
+// stream.
+func (c *Client) runParallelTCPBandwidth(payloadSize int, duration time.Duration, rate *rateLimiter) (int64, time.Duration) {
+	startTime := time.Now()
+
+	var wg sync.WaitGroup
+	var bytesSent int64
+
+	for i := 0; i < c.parallel; i++ {
+		wg.Add(1)
+		go func(stream int) {
+			defer wg.Done()
+			n, _, err := c.runTCPBandwidth(payloadSize, duration, rate)
+			if err != nil {
+				log.Printf("TCP bandwidth stream %d error: %v", stream, err)
+				return
+			}
+			atomic.AddInt64(&bytesSent, n)
+		}(i)
+	}
+	wg.Wait()
+
+	return bytesSent, time.Since(startTime)
+}
+
 func (c *Client) sendControlMessage(command, data string) error {
 	if c.tcpConn == nil {
 		return fmt.Errorf("TCP connection not established")
@@ -576,23 +1190,61 @@ func (c *Client) sendControlMessage(command, data string) error {
 func main() {
 	var (
 		mode        = flag.String("mode", "server", "Mode: 'server' or 'client'")
-		host        = flag.String("host", "127.0.0.1", "Server host (for client mode)")
+		host        = flag.String("host", "127.0.0.1", "Server host (for client mode); default for --control-host/--data-host")
+		controlHost = flag.String("control-host", "", "Control-plane host, overrides --host (client mode)")
+		dataHost    = flag.String("data-host", "", "Data-plane host, overrides --host (client mode); useful when control and data live on distinct VIPs")
 		controlPort = flag.Int("control-port", 5201, "Control port")
 		dataPort    = flag.Int("data-port", 5202, "Data port")
 		testSizes   = flag.String("test-sizes", "32,64,128,256,512,1024,2048,4096,8192,16384,32768,65536,131072,262144,524288,1048576", "Comma-separated list of payload sizes to test (client mode)")
 		duration    = flag.Int("duration", 5, "Test duration per size in seconds (client mode)")
+		latencyOnly = flag.Bool("latency-only", false, "Measure ICMP round-trip latency only, skipping the UDP throughput loop (client mode)")
+		proto       = flag.String("proto", "udp", "Protocol to test: udp, tcp, http, or https (client mode)")
+		test        = flag.String("test", "", "Test kind for proto=tcp: bandwidth (default), cps, or latency (client mode)")
+		parallel    = flag.Int("parallel", 1, "Number of parallel streams per test (client mode, udp/tcp bandwidth only)")
+		targetMbps  = flag.Float64("target-mbps", 0, "Pace the aggregate send rate to this many Mbps instead of sending as fast as possible, 0 disables pacing (client mode)")
+		external    = flag.Bool("external", false, "Attribute UDP flows by a session token instead of source address, so tests survive NAT/L4 load balancers in front of the data port")
+		useTLS      = flag.Bool("tls", false, "Encrypt the TCP control channel with TLS")
+		useDTLS     = flag.Bool("dtls", false, "Encrypt the UDP data channel with DTLS")
+		certFile    = flag.String("cert", "", "TLS/DTLS certificate file; a self-signed cert is generated if omitted")
+		keyFile     = flag.String("key", "", "TLS/DTLS private key file; a self-signed key is generated if omitted")
+		caFile      = flag.String("ca", "", "CA certificate file used to verify the peer (client mode)")
+		insecureSkipVerify = flag.Bool("insecure-skip-verify", false, "Skip TLS/DTLS certificate verification (client mode); required when the server uses a self-signed cert and --ca isn't set")
+		output      = flag.String("output", "text", "Result format: text, json, or csv (client mode); text or json (server mode, periodic stats)")
+		outputFile  = flag.String("output-file", "", "Write results to this file instead of stdout (client mode)")
+		failUnderMbps = flag.Float64("fail-under-mbps", 0, "Exit with a non-zero status if peak bandwidth falls below this many Mbps, 0 disables the check (client mode, for CI health checks)")
 	)
 	flag.Parse()
 
+	security := SecurityOptions{
+		TLS:                *useTLS,
+		DTLS:               *useDTLS,
+		CertFile:           *certFile,
+		KeyFile:            *keyFile,
+		CAFile:             *caFile,
+		InsecureSkipVerify: *insecureSkipVerify,
+	}
+
 	if *mode == "client" {
-		runClient(*host, *controlPort, *dataPort, *testSizes, *duration)
+		kind, err := resolveTestKind(*proto, *test)
+		if err != nil {
+			log.Fatalf("Invalid --proto/--test combination: %v", err)
+		}
+		resolvedControlHost := *host
+		if *controlHost != "" {
+			resolvedControlHost = *controlHost
+		}
+		resolvedDataHost := *host
+		if *dataHost != "" {
+			resolvedDataHost = *dataHost
+		}
+		runClient(resolvedControlHost, resolvedDataHost, *controlPort, *dataPort, *testSizes, *duration, *latencyOnly, kind, *parallel, *targetMbps, *external, security, *output, *outputFile, *failUnderMbps)
 	} else {
-		runServer(*controlPort, *dataPort)
+		runServer(*controlPort, *dataPort, *external, security, *output)
 	}
 }
 
-func runServer(controlPort, dataPort int) {
-	server := NewServer(controlPort, dataPort)
+func runServer(controlPort, dataPort int, external bool, security SecurityOptions, output string) {
+	server := NewServer(controlPort, dataPort, external, security, output)
 	
 	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -618,45 +1270,61 @@ func runServer(controlPort, dataPort int) {
 	log.Println("Server shutdown complete")
 }
 
-func runClient(host string, controlPort, dataPort int, testSizesStr string, durationSec int) {
-	log.Printf("Starting pisp-perf client, connecting to %s:%d", host, controlPort)
-	
+func runClient(controlHost, dataHost string, controlPort, dataPort int, testSizesStr string, durationSec int, latencyOnly bool, kind TestKind, parallel int, targetMbps float64, external bool, security SecurityOptions, output, outputFile string, failUnderMbps float64) {
+	log.Printf("Starting pisp-perf client, connecting to %s:%d (data host: %s, kind: %s, parallel: %d, external: %v, tls: %v, dtls: %v)",
+		controlHost, controlPort, dataHost, kind, parallel, external, security.TLS, security.DTLS)
+
 	// Parse test sizes
 	testSizes, err := parseTestSizes(testSizesStr)
 	if err != nil {
 		log.Fatalf("Failed to parse test sizes: %v", err)
 	}
-	
-	client := NewClient(host, controlPort, dataPort)
+
+	client := NewClient(controlHost, dataHost, controlPort, dataPort, latencyOnly, kind, parallel, targetMbps, external, security)
 	defer client.Close()
-	
+
 	if err := client.Connect(); err != nil {
 		log.Fatalf("Failed to connect to server: %v", err)
 	}
-	
+
 	log.Printf("Connected to server. Running bandwidth tests across %d payload sizes", len(testSizes))
-	
+
+	started := time.Now()
 	results := make([]TestResult, 0, len(testSizes))
-	
+
 	for i, size := range testSizes {
 		log.Printf("Testing payload size %d bytes (%d/%d)", size, i+1, len(testSizes))
-		
+
 		result, err := client.RunBandwidthTest(size, time.Duration(durationSec)*time.Second)
 		if err != nil {
 			log.Printf("Test failed for size %d: %v", size, err)
 			continue
 		}
-		
+
 		results = append(results, result)
-		log.Printf("Result: %d bytes -> %.2f Mbps, %.1f ms latency", 
-			size, result.BandwidthMbps, result.LatencyMs)
-		
+		log.Printf("Result: %d bytes -> %.2f Mbps, %.1f ms avg latency (p50 %.1f, p99 %.1f)",
+			size, result.BandwidthMbps, result.Latency.AvgMs, result.Latency.P50Ms, result.Latency.P99Ms)
+
 		// Small delay between tests
 		time.Sleep(500 * time.Millisecond)
 	}
-	
-	// Print summary
-	printClientSummary(results)
+
+	if err := writeResults(controlHost, started, results, output, outputFile); err != nil {
+		log.Printf("Failed to write results: %v", err)
+	}
+
+	if failUnderMbps > 0 {
+		peakMbps := 0.0
+		for _, r := range results {
+			if r.BandwidthMbps > peakMbps {
+				peakMbps = r.BandwidthMbps
+			}
+		}
+		if peakMbps < failUnderMbps {
+			log.Printf("Peak bandwidth %.2f Mbps is below --fail-under-mbps %.2f", peakMbps, failUnderMbps)
+			os.Exit(1)
+		}
+	}
 }
 
 func parseTestSizes(testSizesStr string) ([]int, error) {
@@ -682,25 +1350,29 @@ func printClientSummary(results []TestResult) {
 	
 	log.Println("\n=== BANDWIDTH TEST RESULTS ===")
 	log.Printf("Completed %d tests", len(results))
-	
+
 	var maxBandwidth float64
 	var totalLatency float64
-	
+
 	for _, result := range results {
 		if result.BandwidthMbps > maxBandwidth {
 			maxBandwidth = result.BandwidthMbps
 		}
-		totalLatency += result.LatencyMs
+		totalLatency += result.Latency.AvgMs
 	}
-	
+
 	avgLatency := totalLatency / float64(len(results))
-	
+
 	log.Printf("Peak bandwidth: %.2f Mbps", maxBandwidth)
 	log.Printf("Average latency: %.1f ms", avgLatency)
-	
+
 	log.Println("\nDetailed results:")
+	log.Printf("  %8s  %10s  %8s  %8s  %8s  %8s  %8s  %12s",
+		"bytes", "Mbps", "min ms", "avg ms", "p50 ms", "p99 ms", "max ms", "handshake ms")
 	for _, result := range results {
-		log.Printf("  %8d bytes: %8.2f Mbps, %6.1f ms", 
-			result.PayloadSize, result.BandwidthMbps, result.LatencyMs)
+		log.Printf("  %8d  %10.2f  %8.2f  %8.2f  %8.2f  %8.2f  %8.2f  %12.2f",
+			result.PayloadSize, result.BandwidthMbps,
+			result.Latency.MinMs, result.Latency.AvgMs, result.Latency.P50Ms, result.Latency.P99Ms, result.Latency.MaxMs,
+			result.HandshakeMs)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// resultsDocument is the --output json schema for a client run: one
+// entry per payload size plus a summary, so results feed cleanly into
+// Grafana/Prometheus pushgateway workflows instead of having to scrape
+// log lines.
+type resultsDocument struct {
+	Host    string           `json:"host"`
+	Started string           `json:"started"`
+	Tests   []testResultJSON `json:"tests"`
+	Summary summaryJSON      `json:"summary"`
+}
+
+type testResultJSON struct {
+	PayloadSize   int          `json:"payload_size"`
+	DurationS     float64      `json:"duration_s"`
+	BytesSent     int64        `json:"bytes_sent"`
+	BandwidthMbps float64      `json:"bandwidth_mbps"`
+	Latency       LatencyStats `json:"latency"`
+	LossPct       float64      `json:"loss_pct"`
+	JitterMs      float64      `json:"jitter_ms"`
+}
+
+type summaryJSON struct {
+	PeakMbps     float64 `json:"peak_mbps"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// buildResultsDocument folds a client run's TestResults into the
+// resultsDocument JSON schema.
+func buildResultsDocument(host string, started time.Time, results []TestResult) resultsDocument {
+	doc := resultsDocument{
+		Host:    host,
+		Started: started.UTC().Format(time.RFC3339),
+		Tests:   make([]testResultJSON, 0, len(results)),
+	}
+
+	var totalLatency float64
+	for _, r := range results {
+		if r.BandwidthMbps > doc.Summary.PeakMbps {
+			doc.Summary.PeakMbps = r.BandwidthMbps
+		}
+		totalLatency += r.Latency.AvgMs
+
+		doc.Tests = append(doc.Tests, testResultJSON{
+			PayloadSize:   r.PayloadSize,
+			DurationS:     r.Duration.Seconds(),
+			BytesSent:     r.BytesSent,
+			BandwidthMbps: r.BandwidthMbps,
+			Latency:       r.Latency,
+			LossPct:       r.LossPct,
+			JitterMs:      r.JitterMs,
+		})
+	}
+	if len(results) > 0 {
+		doc.Summary.AvgLatencyMs = totalLatency / float64(len(results))
+	}
+
+	return doc
+}
+
+// writeResults renders results in the requested format (text, json, or
+// csv) to outputFile, or stdout if outputFile is empty. Text mode keeps
+// using printClientSummary's log.Printf-based output for backwards
+// compatibility with anyone already scraping it.
+func writeResults(host string, started time.Time, results []TestResult, format, outputFile string) error {
+	if format == "" || format == "text" {
+		printClientSummary(results)
+		return nil
+	}
+
+	var rendered string
+	switch format {
+	case "json":
+		doc := buildResultsDocument(host, started, results)
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal results as JSON: %w", err)
+		}
+		rendered = string(data) + "\n"
+	case "csv":
+		var err error
+		rendered, err = renderResultsCSV(results)
+		if err != nil {
+			return fmt.Errorf("failed to render results as CSV: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	if outputFile == "" {
+		fmt.Print(rendered)
+		return nil
+	}
+	return os.WriteFile(outputFile, []byte(rendered), 0o644)
+}
+
+func renderResultsCSV(results []TestResult) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	header := []string{"payload_size", "duration_s", "bytes_sent", "bandwidth_mbps",
+		"min_ms", "avg_ms", "p50_ms", "p99_ms", "max_ms", "loss_pct", "jitter_ms"}
+	if err := w.Write(header); err != nil {
+		return "", err
+	}
+
+	for _, r := range results {
+		row := []string{
+			strconv.Itoa(r.PayloadSize),
+			strconv.FormatFloat(r.Duration.Seconds(), 'f', 2, 64),
+			strconv.FormatInt(r.BytesSent, 10),
+			strconv.FormatFloat(r.BandwidthMbps, 'f', 2, 64),
+			strconv.FormatFloat(r.Latency.MinMs, 'f', 2, 64),
+			strconv.FormatFloat(r.Latency.AvgMs, 'f', 2, 64),
+			strconv.FormatFloat(r.Latency.P50Ms, 'f', 2, 64),
+			strconv.FormatFloat(r.Latency.P99Ms, 'f', 2, 64),
+			strconv.FormatFloat(r.Latency.MaxMs, 'f', 2, 64),
+			strconv.FormatFloat(r.LossPct, 'f', 2, 64),
+			strconv.FormatFloat(r.JitterMs, 'f', 3, 64),
+		}
+		if err := w.Write(row); err != nil {
+			return "", err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// serverStatsJSON is the --output json schema for the server's periodic
+// printStats line.
+type serverStatsJSON struct {
+	Timestamp       string  `json:"timestamp"`
+	Clients         int     `json:"clients"`
+	Testing         int     `json:"testing"`
+	BytesReceived   int64   `json:"bytes_received"`
+	PacketsReceived int64   `json:"packets_received"`
+	BandwidthMbps   float64 `json:"bandwidth_mbps"`
+}
+
+// logServerStats reports one periodic stats sample either as a log line
+// (the historical behavior) or, with --output json, as a single JSON
+// line on stdout so it can be scraped by a pushgateway sidecar.
+func logServerStats(format string, clientCount, testingCount int, stats BandwidthStats) {
+	if format != "json" {
+		if stats.BytesReceived > 0 {
+			log.Printf("Stats - Clients: %d (Testing: %d), Received: %d bytes (%d packets), Current Bandwidth: %.2f Mbps",
+				clientCount, testingCount, stats.BytesReceived, stats.PacketsReceived, stats.BandwidthMbps)
+		}
+		return
+	}
+
+	sample := serverStatsJSON{
+		Timestamp:       time.Now().UTC().Format(time.RFC3339),
+		Clients:         clientCount,
+		Testing:         testingCount,
+		BytesReceived:   stats.BytesReceived,
+		PacketsReceived: stats.PacketsReceived,
+		BandwidthMbps:   stats.BandwidthMbps,
+	}
+	data, err := json.Marshal(sample)
+	if err != nil {
+		log.Printf("Failed to marshal server stats as JSON: %v", err)
+		return
+	}
+	fmt.Println(string(data))
+}
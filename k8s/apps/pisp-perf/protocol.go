@@ -0,0 +1,258 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TestKind identifies which protocol/workload a client wants to run,
+// modeled after ethr's test-kind selection so pigeongame can
+// characterize middleboxes that treat TCP, HTTP, and UDP very
+// differently.
+type TestKind string
+
+const (
+	TestUDPBandwidth   TestKind = "udp_bandwidth"
+	TestTCPBandwidth   TestKind = "tcp_bandwidth"
+	TestTCPCPS         TestKind = "tcp_cps"
+	TestTCPLatency     TestKind = "tcp_latency"
+	TestHTTPBandwidth  TestKind = "http_bandwidth"
+	TestHTTPSBandwidth TestKind = "https_bandwidth"
+)
+
+// resolveTestKind combines the --proto and --test flags into a single
+// TestKind, the way the control channel negotiates it.
+func resolveTestKind(proto, test string) (TestKind, error) {
+	switch proto {
+	case "udp":
+		if test != "" && test != "bandwidth" {
+			return "", fmt.Errorf("proto udp only supports test=bandwidth, got %q", test)
+		}
+		return TestUDPBandwidth, nil
+	case "tcp":
+		switch test {
+		case "", "bandwidth":
+			return TestTCPBandwidth, nil
+		case "cps":
+			return TestTCPCPS, nil
+		case "latency":
+			return TestTCPLatency, nil
+		default:
+			return "", fmt.Errorf("unknown test %q for proto tcp", test)
+		}
+	case "http":
+		return TestHTTPBandwidth, nil
+	case "https":
+		return TestHTTPSBandwidth, nil
+	default:
+		return "", fmt.Errorf("unknown proto %q", proto)
+	}
+}
+
+// Port offsets for the protocol-specific listeners Server.Start opens
+// alongside the original UDP bandwidth port, so each test kind gets an
+// unambiguous socket instead of having to be disambiguated after accept.
+const (
+	tcpBandwidthPortOffset   = 1
+	tcpCPSPortOffset         = 2
+	httpBandwidthPortOffset  = 3
+	httpsBandwidthPortOffset = 4
+)
+
+// acceptTCPBandwidthConnections accepts connections on the TCP
+// bandwidth port and streams their payload into the shared stats
+// counters, the TCP analogue of handleUDPData.
+func (s *Server) acceptTCPBandwidthConnections() {
+	for {
+		conn, err := s.tcpBWListener.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		go s.drainTCPBandwidthConn(conn)
+	}
+}
+
+func (s *Server) drainTCPBandwidthConn(conn net.Conn) {
+	defer conn.Close()
+	flowKey := conn.RemoteAddr().String()
+	client := s.clientByIP(flowKey)
+	buf := make([]byte, 65536)
+	for {
+		conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+		n, err := conn.Read(buf)
+		if n > 0 && client != nil {
+			s.updateStats(client, flowKey, n)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// acceptTCPCPSConnections accepts and immediately closes every
+// connection on the CPS port, counting them as packets in the shared
+// stats so TCP connections-per-second shows up the same way a
+// bandwidth test's packet rate does.
+func (s *Server) acceptTCPCPSConnections() {
+	for {
+		conn, err := s.cpsListener.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		if client := s.clientByIP(conn.RemoteAddr().String()); client != nil {
+			s.updateStats(client, conn.RemoteAddr().String(), 0)
+		}
+		conn.Close()
+	}
+}
+
+// startHTTPBandwidthServers brings up the HTTP and HTTPS bandwidth
+// endpoints. Both use the same streaming handler; HTTPS additionally
+// wraps it in a self-signed cert so the tool works without the
+// operator provisioning real certificates.
+func (s *Server) startHTTPBandwidthServers(host string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleHTTPBandwidth)
+
+	httpAddr := fmt.Sprintf(":%d", s.dataPort+httpBandwidthPortOffset)
+	httpListener, err := net.Listen("tcp", httpAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for HTTP bandwidth on %s: %w", httpAddr, err)
+	}
+	s.httpServer = &http.Server{Handler: mux}
+	go s.httpServer.Serve(httpListener)
+
+	cert, err := generateSelfSignedCert(host)
+	if err != nil {
+		return fmt.Errorf("failed to generate self-signed cert for HTTPS bandwidth: %w", err)
+	}
+	httpsAddr := fmt.Sprintf(":%d", s.dataPort+httpsBandwidthPortOffset)
+	httpsListener, err := tls.Listen("tcp", httpsAddr, &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		return fmt.Errorf("failed to listen for HTTPS bandwidth on %s: %w", httpsAddr, err)
+	}
+	s.httpsServer = &http.Server{Handler: mux}
+	go s.httpsServer.Serve(httpsListener)
+
+	return nil
+}
+
+// handleHTTPBandwidth streams zero-filled chunks for ?duration=N
+// seconds (default 10), recording every byte written against the
+// requesting client's session stats.
+func (s *Server) handleHTTPBandwidth(w http.ResponseWriter, r *http.Request) {
+	duration := 10 * time.Second
+	if d := r.URL.Query().Get("duration"); d != "" {
+		if secs, err := strconv.Atoi(d); err == nil && secs > 0 {
+			duration = time.Duration(secs) * time.Second
+		}
+	}
+
+	chunk := make([]byte, 32*1024)
+	flusher, _ := w.(http.Flusher)
+	flowKey := r.RemoteAddr
+	client := s.clientByIP(flowKey)
+
+	deadline := time.Now().Add(duration)
+	w.WriteHeader(http.StatusOK)
+	for time.Now().Before(deadline) {
+		n, err := w.Write(chunk)
+		if n > 0 && client != nil {
+			s.updateStats(client, flowKey, n)
+		}
+		if err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// runTCPBandwidth streams payloadSize-sized chunks over a single TCP
+// connection for duration, the TCP analogue of the UDP send loop. rate,
+// if non-nil, paces the writes instead of sending as fast as possible.
+func (c *Client) runTCPBandwidth(payloadSize int, duration time.Duration, rate *rateLimiter) (int64, time.Duration, error) {
+	addr := fmt.Sprintf("%s:%d", c.dataHost, c.dataPort+tcpBandwidthPortOffset)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to connect to TCP bandwidth port: %v", err)
+	}
+	defer conn.Close()
+
+	data := make([]byte, payloadSize)
+	start := time.Now()
+	end := start.Add(duration)
+	var bytesSent int64
+
+	for time.Now().Before(end) {
+		if rate != nil {
+			rate.take(len(data))
+		}
+		n, err := conn.Write(data)
+		if err != nil {
+			break
+		}
+		bytesSent += int64(n)
+	}
+
+	return bytesSent, time.Since(start), nil
+}
+
+// runTCPCPS repeatedly dials and closes the CPS port for duration,
+// counting how many connections it managed to complete.
+func (c *Client) runTCPCPS(duration time.Duration) (int64, time.Duration, error) {
+	addr := fmt.Sprintf("%s:%d", c.dataHost, c.dataPort+tcpCPSPortOffset)
+	start := time.Now()
+	end := start.Add(duration)
+	var count int64
+
+	for time.Now().Before(end) {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		count++
+	}
+
+	return count, time.Since(start), nil
+}
+
+// runHTTPBandwidth issues a single streaming GET against the HTTP (or
+// HTTPS) bandwidth endpoint and counts the bytes it reads back.
+func (c *Client) runHTTPBandwidth(duration time.Duration, useTLS bool) (int64, time.Duration, error) {
+	scheme, portOffset := "http", httpBandwidthPortOffset
+	httpClient := &http.Client{}
+	if useTLS {
+		scheme, portOffset = "https", httpsBandwidthPortOffset
+		httpClient.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+
+	url := fmt.Sprintf("%s://%s:%d/?duration=%d", scheme, c.dataHost, c.dataPort+portOffset, int(duration.Seconds()))
+	start := time.Now()
+
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil && err != io.EOF {
+		return n, time.Since(start), fmt.Errorf("error reading HTTP bandwidth response: %v", err)
+	}
+
+	return n, time.Since(start), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestResolveTestKind(t *testing.T) {
+	cases := []struct {
+		proto, test string
+		want        TestKind
+	}{
+		{"udp", "", TestUDPBandwidth},
+		{"udp", "bandwidth", TestUDPBandwidth},
+		{"tcp", "", TestTCPBandwidth},
+		{"tcp", "bandwidth", TestTCPBandwidth},
+		{"tcp", "cps", TestTCPCPS},
+		{"tcp", "latency", TestTCPLatency},
+		{"http", "", TestHTTPBandwidth},
+		{"https", "", TestHTTPSBandwidth},
+	}
+
+	for _, c := range cases {
+		got, err := resolveTestKind(c.proto, c.test)
+		if err != nil {
+			t.Errorf("resolveTestKind(%q, %q) returned error: %v", c.proto, c.test, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("resolveTestKind(%q, %q) = %q, want %q", c.proto, c.test, got, c.want)
+		}
+	}
+}
+
+func TestResolveTestKindErrors(t *testing.T) {
+	cases := []struct {
+		proto, test string
+	}{
+		{"udp", "cps"},
+		{"tcp", "nonsense"},
+		{"carrier-pigeon", ""},
+	}
+
+	for _, c := range cases {
+		if _, err := resolveTestKind(c.proto, c.test); err == nil {
+			t.Errorf("resolveTestKind(%q, %q) expected an error, got nil", c.proto, c.test)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a simple byte-budget token bucket used to pace the send
+// loop at a calibrated rate (--target-mbps) instead of sending as fast as
+// possible. It is shared across every parallel stream in a test, so the
+// target applies to the aggregate rate, not per-stream.
+type rateLimiter struct {
+	mu         sync.Mutex
+	tokens     float64
+	maxTokens  float64
+	refillRate float64 // bytes per second
+	last       time.Time
+}
+
+// newRateLimiter returns a rateLimiter capped at targetMbps, or nil if
+// targetMbps is zero (no limit).
+func newRateLimiter(targetMbps float64) *rateLimiter {
+	if targetMbps <= 0 {
+		return nil
+	}
+	bytesPerSec := targetMbps * 1000000 / 8
+	return &rateLimiter{
+		tokens:     bytesPerSec,
+		maxTokens:  bytesPerSec,
+		refillRate: bytesPerSec,
+		last:       time.Now(),
+	}
+}
+
+// take blocks until n bytes worth of budget are available.
+func (r *rateLimiter) take(n int) {
+	for {
+		r.mu.Lock()
+		now := time.Now()
+		r.tokens += now.Sub(r.last).Seconds() * r.refillRate
+		r.last = now
+		if r.tokens > r.maxTokens {
+			r.tokens = r.maxTokens
+		}
+
+		if r.tokens >= float64(n) {
+			r.tokens -= float64(n)
+			r.mu.Unlock()
+			return
+		}
+
+		wait := time.Duration((float64(n) - r.tokens) / r.refillRate * float64(time.Second))
+		r.mu.Unlock()
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/pion/dtls/v2"
+)
+
+// SecurityOptions bundles the --tls/--dtls/--cert/--key/--ca flags a
+// Server or Client needs to encrypt its control and/or data channel.
+// Kept as a single value instead of growing NewServer/NewClient by five
+// more positional params.
+type SecurityOptions struct {
+	TLS                bool
+	DTLS               bool
+	CertFile           string
+	KeyFile            string
+	CAFile             string
+	InsecureSkipVerify bool
+}
+
+// loadCertificate returns the CertFile/KeyFile pair, or a freshly
+// generated self-signed certificate for host if either is omitted, so
+// --tls/--dtls work out of the box without the operator provisioning
+// real certificates first.
+func (o SecurityOptions) loadCertificate(host string) (tls.Certificate, error) {
+	if o.CertFile == "" || o.KeyFile == "" {
+		return generateSelfSignedCert(host)
+	}
+	return tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+}
+
+// loadCAPool reads CAFile into a cert pool for verifying the peer, or
+// returns nil if CAFile is empty, in which case callers fall back to the
+// system pool (or InsecureSkipVerify, for the common case of a
+// self-signed cert with no shared CA).
+func (o SecurityOptions) loadCAPool() (*x509.CertPool, error) {
+	if o.CAFile == "" {
+		return nil, nil
+	}
+	pemBytes, err := os.ReadFile(o.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file %s: %w", o.CAFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+	}
+	return pool, nil
+}
+
+func (o SecurityOptions) serverTLSConfig(host string) (*tls.Config, error) {
+	cert, err := o.loadCertificate(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+func (o SecurityOptions) clientTLSConfig(serverName string) (*tls.Config, error) {
+	pool, err := o.loadCAPool()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		ServerName:         serverName,
+		RootCAs:            pool,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}, nil
+}
+
+func (o SecurityOptions) serverDTLSConfig(host string) (*dtls.Config, error) {
+	cert, err := o.loadCertificate(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load DTLS certificate: %w", err)
+	}
+	return &dtls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+func (o SecurityOptions) clientDTLSConfig(serverName string) (*dtls.Config, error) {
+	pool, err := o.loadCAPool()
+	if err != nil {
+		return nil, err
+	}
+	return &dtls.Config{
+		ServerName:         serverName,
+		RootCAs:            pool,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// udpHeaderSize is the fixed 24-byte binary header prepended to every
+// UDP bandwidth-test payload: magic, flow ID, sequence number, and send
+// timestamp, all network byte order. It lets the server detect loss,
+// reordering, duplication, and jitter without needing a clock
+// synchronized to the client's.
+const udpHeaderSize = 24
+
+const udpHeaderMagic uint32 = 0x50495350 // "PISP"
+
+// udpSessionTokenSize is the number of raw bytes of the external-mode
+// session token prepended ahead of the udpHeader on every datagram.
+const udpSessionTokenSize = 16
+
+// udpSeqWindowBits bounds the sliding window used to tell a reordered
+// packet apart from a lost one: only the last udpSeqWindowBits sequence
+// numbers below HighestSeq are remembered, so state stays fixed-size no
+// matter how long a test runs.
+const udpSeqWindowBits = 4096
+const udpSeqWindowWords = udpSeqWindowBits / 64
+
+type udpHeader struct {
+	FlowID     uint32
+	Seq        uint64
+	SendTimeNs uint64
+}
+
+// putUDPHeader writes a udpHeader for flowID/seq into the first
+// udpHeaderSize bytes of buf, stamping the current send time. buf must
+// be at least udpHeaderSize bytes long.
+func putUDPHeader(buf []byte, flowID uint32, seq uint64) {
+	binary.BigEndian.PutUint32(buf[0:4], udpHeaderMagic)
+	binary.BigEndian.PutUint32(buf[4:8], flowID)
+	binary.BigEndian.PutUint64(buf[8:16], seq)
+	binary.BigEndian.PutUint64(buf[16:24], uint64(time.Now().UnixNano()))
+}
+
+// parseUDPHeader reads a udpHeader from the front of data. ok is false
+// if data is too short or doesn't start with the expected magic, in
+// which case the caller should fall back to treating data as an
+// unstructured legacy payload.
+func parseUDPHeader(data []byte) (udpHeader, bool) {
+	if len(data) < udpHeaderSize {
+		return udpHeader{}, false
+	}
+	if binary.BigEndian.Uint32(data[0:4]) != udpHeaderMagic {
+		return udpHeader{}, false
+	}
+	return udpHeader{
+		FlowID:     binary.BigEndian.Uint32(data[4:8]),
+		Seq:        binary.BigEndian.Uint64(data[8:16]),
+		SendTimeNs: binary.BigEndian.Uint64(data[16:24]),
+	}, true
+}
+
+// recordSeq folds one packet's sequence number and send timestamp into
+// the flow's loss/reorder/duplicate counters and its RFC 3550-style
+// jitter estimate (J = J + (|D(i-1,i)| - J)/16, where D is the
+// difference of interarrival transit times). It assumes the caller
+// already holds whatever lock guards this BandwidthStats.
+func (stats *BandwidthStats) recordSeq(seq uint64, sendTimeNs uint64, arrival time.Time) {
+	transit := arrival.UnixNano() - int64(sendTimeNs)
+	if stats.haveTransit {
+		d := transit - stats.lastTransitNs
+		if d < 0 {
+			d = -d
+		}
+		stats.jitterNs += (float64(d) - stats.jitterNs) / 16
+		stats.JitterMs = stats.jitterNs / float64(time.Millisecond)
+	}
+	stats.lastTransitNs = transit
+	stats.haveTransit = true
+
+	if !stats.seenAny {
+		stats.seenAny = true
+		stats.HighestSeq = seq
+		stats.markSeen(seq)
+		return
+	}
+
+	switch {
+	case seq > stats.HighestSeq:
+		gap := seq - stats.HighestSeq
+		if gap > udpSeqWindowBits {
+			stats.seenWindow = [udpSeqWindowWords]uint64{}
+		} else {
+			for missing := stats.HighestSeq + 1; missing < seq; missing++ {
+				stats.clearSeen(missing)
+			}
+		}
+		stats.PacketsLost += int64(gap - 1)
+		stats.HighestSeq = seq
+		stats.markSeen(seq)
+
+	case seq == stats.HighestSeq:
+		stats.PacketsDuplicated++
+
+	case stats.HighestSeq-seq <= udpSeqWindowBits:
+		if stats.isSeen(seq) {
+			stats.PacketsDuplicated++
+		} else {
+			stats.markSeen(seq)
+			stats.PacketsReordered++
+			if stats.PacketsLost > 0 {
+				stats.PacketsLost--
+			}
+		}
+
+	default:
+		// Too far behind the window to tell a genuine late arrival from
+		// noise; count it as a duplicate rather than risk corrupting the
+		// loss count.
+		stats.PacketsDuplicated++
+	}
+}
+
+func (stats *BandwidthStats) markSeen(seq uint64) {
+	idx := seq % udpSeqWindowBits
+	stats.seenWindow[idx/64] |= 1 << (idx % 64)
+}
+
+func (stats *BandwidthStats) clearSeen(seq uint64) {
+	idx := seq % udpSeqWindowBits
+	stats.seenWindow[idx/64] &^= 1 << (idx % 64)
+}
+
+func (stats *BandwidthStats) isSeen(seq uint64) bool {
+	idx := seq % udpSeqWindowBits
+	return stats.seenWindow[idx/64]&(1<<(idx%64)) != 0
+}
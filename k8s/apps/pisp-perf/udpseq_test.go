@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarkSeenAndIsSeen(t *testing.T) {
+	stats := &BandwidthStats{}
+
+	if stats.isSeen(42) {
+		t.Fatal("isSeen(42) = true before markSeen")
+	}
+	stats.markSeen(42)
+	if !stats.isSeen(42) {
+		t.Fatal("isSeen(42) = false after markSeen")
+	}
+	if stats.isSeen(43) {
+		t.Fatal("isSeen(43) = true, want false")
+	}
+
+	stats.clearSeen(42)
+	if stats.isSeen(42) {
+		t.Fatal("isSeen(42) = true after clearSeen")
+	}
+}
+
+func TestRecordSeqInOrder(t *testing.T) {
+	stats := &BandwidthStats{}
+	now := time.Now()
+
+	stats.recordSeq(1, uint64(now.UnixNano()), now)
+	stats.recordSeq(2, uint64(now.UnixNano()), now.Add(time.Millisecond))
+	stats.recordSeq(3, uint64(now.UnixNano()), now.Add(2*time.Millisecond))
+
+	if stats.PacketsLost != 0 {
+		t.Errorf("PacketsLost = %d, want 0", stats.PacketsLost)
+	}
+	if stats.PacketsReordered != 0 {
+		t.Errorf("PacketsReordered = %d, want 0", stats.PacketsReordered)
+	}
+	if stats.PacketsDuplicated != 0 {
+		t.Errorf("PacketsDuplicated = %d, want 0", stats.PacketsDuplicated)
+	}
+	if stats.HighestSeq != 3 {
+		t.Errorf("HighestSeq = %d, want 3", stats.HighestSeq)
+	}
+}
+
+func TestRecordSeqDetectsLoss(t *testing.T) {
+	stats := &BandwidthStats{}
+	now := time.Now()
+
+	stats.recordSeq(1, uint64(now.UnixNano()), now)
+	stats.recordSeq(5, uint64(now.UnixNano()), now.Add(time.Millisecond)) // seq 2,3,4 missing
+
+	if stats.PacketsLost != 3 {
+		t.Errorf("PacketsLost = %d, want 3", stats.PacketsLost)
+	}
+}
+
+func TestRecordSeqDetectsReorderAndRecoversLoss(t *testing.T) {
+	stats := &BandwidthStats{}
+	now := time.Now()
+
+	stats.recordSeq(1, uint64(now.UnixNano()), now)
+	stats.recordSeq(3, uint64(now.UnixNano()), now.Add(time.Millisecond)) // seq 2 counted lost
+	if stats.PacketsLost != 1 {
+		t.Fatalf("PacketsLost = %d, want 1 before late arrival", stats.PacketsLost)
+	}
+
+	stats.recordSeq(2, uint64(now.UnixNano()), now.Add(2*time.Millisecond)) // seq 2 arrives late
+	if stats.PacketsReordered != 1 {
+		t.Errorf("PacketsReordered = %d, want 1", stats.PacketsReordered)
+	}
+	if stats.PacketsLost != 0 {
+		t.Errorf("PacketsLost = %d, want 0 after reordered packet recovered it", stats.PacketsLost)
+	}
+}
+
+func TestRecordSeqDetectsDuplicate(t *testing.T) {
+	stats := &BandwidthStats{}
+	now := time.Now()
+
+	stats.recordSeq(1, uint64(now.UnixNano()), now)
+	stats.recordSeq(1, uint64(now.UnixNano()), now.Add(time.Millisecond))
+
+	if stats.PacketsDuplicated != 1 {
+		t.Errorf("PacketsDuplicated = %d, want 1", stats.PacketsDuplicated)
+	}
+}
+
+func TestPutAndParseUDPHeaderRoundTrip(t *testing.T) {
+	buf := make([]byte, udpHeaderSize)
+	putUDPHeader(buf, 7, 99)
+
+	hdr, ok := parseUDPHeader(buf)
+	if !ok {
+		t.Fatal("parseUDPHeader returned ok=false for a just-written header")
+	}
+	if hdr.FlowID != 7 || hdr.Seq != 99 {
+		t.Errorf("got FlowID=%d Seq=%d, want FlowID=7 Seq=99", hdr.FlowID, hdr.Seq)
+	}
+}
+
+func TestParseUDPHeaderRejectsShortOrUnmarkedData(t *testing.T) {
+	if _, ok := parseUDPHeader([]byte{1, 2, 3}); ok {
+		t.Error("parseUDPHeader on short data returned ok=true")
+	}
+	if _, ok := parseUDPHeader(make([]byte, udpHeaderSize)); ok {
+		t.Error("parseUDPHeader on all-zero data returned ok=true")
+	}
+}
@@ -14,23 +14,95 @@ import (
 	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/cni/pkg/version"
+	"github.com/containernetworking/plugins/pkg/ipam"
+	"github.com/pigeongame/cni/pkg/ethtool"
+	"github.com/pigeongame/cni/pkg/nsutil"
+	"github.com/pigeongame/cni/pkg/shaping"
+	"github.com/pigeongame/cni/pkg/sriov"
+	"github.com/pigeongame/cni/pkg/sysctl"
 	"github.com/vishvananda/netlink"
-	"github.com/vishvananda/netns"
 )
 
 const (
-	logFile = "/var/log/pigeon-cni-plugin.log"
-	ipStore = "/tmp/reserved_ips"
+	logFile          = "/var/log/pigeon-cni-plugin.log"
+	ipStore          = "/tmp/reserved_ips"
+	bridgeNamePrefix = "pibr-"
+	gatewayNSPrefix  = "pigw-"
 )
 
 type NetConf struct {
 	types.NetConf
-	ClientAddress    string `json:"client_address"`
-	GwAddress        string `json:"gw_address"`
-	Subnet           string `json:"subnet"`
-	ClientInterface  string `json:"client_interface"`
-	GatewayInterface string `json:"gateway_interface"`
-	Name             string `json:"name"`
+	ClientAddress    string            `json:"client_address"`
+	GwAddress        string            `json:"gw_address"`
+	Subnet           string            `json:"subnet"`
+	ClientInterface  string            `json:"client_interface"`
+	GatewayInterface string            `json:"gateway_interface"`
+	Name             string            `json:"name"`
+	Mode             string            `json:"mode,omitempty"`
+	Sysctls          map[string]string `json:"sysctls,omitempty"`
+	Offloads         map[string]bool   `json:"offloads,omitempty"`
+	PFName           string            `json:"pf_name,omitempty"`
+	VFIndex          *int              `json:"vf_index,omitempty"`
+	Bandwidth        *shaping.Limits   `json:"bandwidth,omitempty"`
+}
+
+// bridgeName returns the stable, deterministic name of the persistent
+// bridge used for a "bridge" mode network, so any client ADD can find it
+// without needing any shared state beyond the network's name.
+func bridgeName(name string) string {
+	return bridgeNamePrefix + name
+}
+
+// gatewayNSPath returns the /var/run/netns symlink a "bridge" mode
+// gateway ADD publishes its netns under, so later client ADDs for the
+// same network can find it.
+func gatewayNSPath(name string) string {
+	return filepath.Join("/var/run/netns", gatewayNSPrefix+name)
+}
+
+// reportedInterfaceName returns the name the CNI result (and CHECK) should
+// use for args.IfName's interface. It's args.IfName in every mode except
+// a bridge-mode gateway: handleGatewayBridge never renames the bridge
+// device away from its stable bridgeName(conf.Name), since later client
+// ADDs need to find it there, so the result has to report that real name
+// instead of lying about it being args.IfName.
+func reportedInterfaceName(conf *NetConf, args *skel.CmdArgs) string {
+	if conf.Mode == "bridge" && args.IfName == conf.GatewayInterface {
+		return bridgeName(conf.Name)
+	}
+	return args.IfName
+}
+
+// shortContainerID returns a short, interface-name-safe prefix of a
+// container ID, matching the length Docker/containerd normally display.
+func shortContainerID(containerID string) string {
+	if len(containerID) > 8 {
+		return containerID[:8]
+	}
+	return containerID
+}
+
+// defaultSysctls returns the kernel knobs the plugin sets unless the user
+// overrides them via conf.Sysctls. Only the gateway side needs IPv4
+// forwarding enabled, since that's the only interface routing traffic
+// between clients.
+func defaultSysctls(isGateway bool) map[string]string {
+	if !isGateway {
+		return nil
+	}
+	return map[string]string{"net.ipv4.ip_forward": "1"}
+}
+
+// defaultOffloads returns the offload features the plugin disables unless
+// the user overrides them via conf.Offloads. Both veth endpoints get
+// tx-checksum and TSO turned off, equivalent to `ethtool -K tx off`: these
+// offloads only help on real NICs and add overhead on a virtual link.
+func defaultOffloads() map[string]bool {
+	return map[string]bool{
+		"tx-checksum-ip-generic": false,
+		"tx-checksum-ipv4":       false,
+		"tso":                    false,
+	}
 }
 
 func init() {
@@ -82,6 +154,19 @@ func cmdAdd(args *skel.CmdArgs) error {
 		return fmt.Errorf("failed to create client netns symlink: %v", err)
 	}
 
+	if conf.Mode == "bridge" && args.IfName == conf.GatewayInterface {
+		// Publish this network's gateway netns under a name keyed only by
+		// conf.Name, so any number of later client ADDs can find it and
+		// attach to its bridge without colliding with each other.
+		gwPath := gatewayNSPath(conf.Name)
+		if err := os.Remove(gwPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: failed to remove existing gateway netns symlink: %v", err)
+		}
+		if err := os.Symlink(args.Netns, gwPath); err != nil {
+			return fmt.Errorf("failed to create gateway netns symlink: %v", err)
+		}
+	}
+
 	var address string
 	var mac net.HardwareAddr
 	var err error
@@ -106,12 +191,31 @@ func cmdAdd(args *skel.CmdArgs) error {
 		CNIVersion: conf.CNIVersion,
 		Interfaces: []*current.Interface{
 			{
-				Name:    args.IfName,
+				Name:    reportedInterfaceName(&conf, args),
 				Mac:     mac.String(),
 				Sandbox: args.Netns,
 			},
 		},
-		IPs: []*current.IPConfig{
+	}
+
+	if conf.IPAM.Type != "" {
+		log.Printf("Delegating IP assignment to IPAM plugin %s", conf.IPAM.Type)
+
+		ipamResult, err := allocateIPAM(&conf, args)
+		if err != nil {
+			return err
+		}
+
+		result.IPs = ipamResult.IPs
+		result.Routes = ipamResult.Routes
+		result.DNS = ipamResult.DNS
+
+		if err := applyIPAMResult(args, result); err != nil {
+			ipam.ExecDel(conf.IPAM.Type, args.StdinData)
+			return err
+		}
+	} else {
+		result.IPs = []*current.IPConfig{
 			{
 				Address: net.IPNet{
 					IP:   net.ParseIP(strings.Split(address, "/")[0]),
@@ -120,15 +224,67 @@ func cmdAdd(args *skel.CmdArgs) error {
 				Gateway:   net.ParseIP("0.0.0.0"),
 				Interface: &[]int{0}[0],
 			},
-		},
+		}
 	}
 
 	return types.PrintResult(result, conf.CNIVersion)
 }
 
+// allocateIPAM invokes the delegated IPAM plugin named in conf.IPAM.Type
+// (e.g. host-local, dhcp, static) and normalizes whatever result it
+// returns into a current.Result so it can be applied to the interface.
+func allocateIPAM(conf *NetConf, args *skel.CmdArgs) (*current.Result, error) {
+	r, err := ipam.ExecAdd(conf.IPAM.Type, args.StdinData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run IPAM plugin %s: %v", conf.IPAM.Type, err)
+	}
+
+	ipamResult, err := current.NewResultFromResult(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse result from IPAM plugin %s: %v", conf.IPAM.Type, err)
+	}
+
+	if len(ipamResult.IPs) == 0 {
+		return nil, fmt.Errorf("IPAM plugin %s returned no IP addresses", conf.IPAM.Type)
+	}
+
+	// The IPAM plugin only knows about addresses, not interfaces, so point
+	// every returned address at the single interface we're configuring.
+	for _, ipc := range ipamResult.IPs {
+		ipc.Interface = current.Int(0)
+	}
+
+	return ipamResult, nil
+}
+
+// applyIPAMResult enters the target container namespace and configures
+// args.IfName with the addresses and routes the IPAM plugin returned.
+// result must be the cmdAdd-level result (with Interfaces already pointing
+// at args.IfName), not the bare value returned by allocateIPAM - the IPAM
+// plugin itself never populates Interfaces, and ipam.ConfigureIface
+// rejects every IP whose Interface index doesn't resolve into one.
+func applyIPAMResult(args *skel.CmdArgs, result *current.Result) error {
+	targetNS, err := nsutil.GetFromPath(args.Netns)
+	if err != nil {
+		return fmt.Errorf("failed to get netns: %v", err)
+	}
+	defer targetNS.Close()
+
+	return targetNS.Do(func(_ nsutil.NetNS) error {
+		if err := ipam.ConfigureIface(args.IfName, result); err != nil {
+			return fmt.Errorf("failed to configure %s from IPAM result: %v", args.IfName, err)
+		}
+		return nil
+	})
+}
+
 func handleGateway(args *skel.CmdArgs, conf *NetConf) (net.HardwareAddr, error) {
+	if conf.Mode == "bridge" {
+		return handleGatewayBridge(args, conf)
+	}
+
 	// Get the target network namespace
-	targetNS, err := netns.GetFromPath(args.Netns)
+	targetNS, err := nsutil.GetFromPath(args.Netns)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get netns: %v", err)
 	}
@@ -142,15 +298,75 @@ func handleGateway(args *skel.CmdArgs, conf *NetConf) (net.HardwareAddr, error)
 	}
 
 	// Move interface to container namespace
-	if err := netlink.LinkSetNsFd(link, int(targetNS)); err != nil {
+	if err := netlink.LinkSetNsFd(link, targetNS.Fd()); err != nil {
 		return nil, fmt.Errorf("failed to move interface to namespace: %v", err)
 	}
 
 	// Enter the network namespace to configure the interface
-	return configureInterfaceInNS(targetNS, vethName, conf.GwAddress, args.IfName)
+	return configureInterfaceInNS(targetNS, vethName, conf.GwAddress, args.IfName, conf, true)
+}
+
+// handleGatewayBridge creates (or reuses, on a repeat ADD) the persistent
+// bridge for conf.Name inside the gateway netns and assigns it the
+// gateway address. Unlike the single-client veth path, this bridge
+// outlives any one client ADD/DEL so many clients can share it.
+func handleGatewayBridge(args *skel.CmdArgs, conf *NetConf) (net.HardwareAddr, error) {
+	targetNS, err := nsutil.GetFromPath(args.Netns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get netns: %v", err)
+	}
+	defer targetNS.Close()
+
+	brName := bridgeName(conf.Name)
+	var mac net.HardwareAddr
+
+	err = targetNS.Do(func(_ nsutil.NetNS) error {
+		link, err := netlink.LinkByName(brName)
+		if err != nil {
+			if _, ok := err.(netlink.LinkNotFoundError); !ok {
+				return fmt.Errorf("failed to look up bridge %s: %v", brName, err)
+			}
+			br := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: brName}}
+			if err := netlink.LinkAdd(br); err != nil && !strings.Contains(err.Error(), "file exists") {
+				return fmt.Errorf("failed to create bridge %s: %v", brName, err)
+			}
+			link, err = netlink.LinkByName(brName)
+			if err != nil {
+				return fmt.Errorf("failed to find bridge %s after creating it: %v", brName, err)
+			}
+		}
+
+		if err := netlink.LinkSetUp(link); err != nil {
+			return fmt.Errorf("failed to bring bridge %s up: %v", brName, err)
+		}
+
+		addr, err := netlink.ParseAddr(conf.GwAddress)
+		if err != nil {
+			return fmt.Errorf("failed to parse gateway address %s: %v", conf.GwAddress, err)
+		}
+		if err := netlink.AddrAdd(link, addr); err != nil && !strings.Contains(err.Error(), "file exists") {
+			return fmt.Errorf("failed to add gateway address to %s: %v", brName, err)
+		}
+
+		if err := sysctl.Set("net.ipv4.ip_forward", "1"); err != nil {
+			return err
+		}
+
+		mac = link.Attrs().HardwareAddr
+		return nil
+	})
+
+	return mac, err
 }
 
 func handleClient(args *skel.CmdArgs, conf *NetConf) (net.HardwareAddr, error) {
+	if conf.Mode == "bridge" {
+		return handleClientBridge(args, conf)
+	}
+	if conf.Mode == "sriov" {
+		return handleClientSRIOV(args, conf)
+	}
+
 	// Create veth pair
 	clientVeth := fmt.Sprintf("pisp%s", args.IfName)
 	gatewayVeth := fmt.Sprintf("pisp%s", conf.GatewayInterface)
@@ -165,9 +381,20 @@ func handleClient(args *skel.CmdArgs, conf *NetConf) (net.HardwareAddr, error) {
 		return nil, fmt.Errorf("failed to create veth pair: %v", err)
 	}
 
+	gatewayLink, err := netlink.LinkByName(gatewayVeth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find host veth %s: %v", gatewayVeth, err)
+	}
+	if err := netlink.LinkSetUp(gatewayLink); err != nil {
+		return nil, fmt.Errorf("failed to bring up host veth %s: %v", gatewayVeth, err)
+	}
+	if err := shaping.Setup(gatewayVeth, args.ContainerID, conf.Bandwidth); err != nil {
+		return nil, err
+	}
+
 	// Get the target network namespace
 	clientNSPath := fmt.Sprintf("%s-%s-%s", conf.ClientInterface, conf.Name, conf.GatewayInterface)
-	targetNS, err := netns.GetFromPath(filepath.Join("/var/run/netns", clientNSPath))
+	targetNS, err := nsutil.GetFromPath(filepath.Join("/var/run/netns", clientNSPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client netns: %v", err)
 	}
@@ -179,40 +406,141 @@ func handleClient(args *skel.CmdArgs, conf *NetConf) (net.HardwareAddr, error) {
 		return nil, fmt.Errorf("failed to find client veth: %v", err)
 	}
 
-	if err := netlink.LinkSetNsFd(clientLink, int(targetNS)); err != nil {
+	if err := netlink.LinkSetNsFd(clientLink, targetNS.Fd()); err != nil {
 		return nil, fmt.Errorf("failed to move client interface to namespace: %v", err)
 	}
 
 	// Configure client interface in its namespace
-	mac, err := configureInterfaceInNS(targetNS, clientVeth, conf.ClientAddress, args.IfName)
+	mac, err := configureInterfaceInNS(targetNS, clientVeth, conf.ClientAddress, args.IfName, conf, false)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set up routing in client namespace
-	if err := setupClientRouting(targetNS, clientVeth, conf.GwAddress); err != nil {
+	if err := setupClientRouting(targetNS, clientVeth, conf.GwAddress, conf); err != nil {
+		return nil, err
+	}
+
+	return mac, nil
+}
+
+// handleClientBridge creates a veth pair for this client, plugs the host
+// end into the shared bridge running inside the gateway netns, and
+// configures the container end with conf.ClientAddress. Unlike the
+// single-client path above, both veth names are keyed off the
+// container ID, since many clients attach to the same gateway.
+func handleClientBridge(args *skel.CmdArgs, conf *NetConf) (net.HardwareAddr, error) {
+	id := shortContainerID(args.ContainerID)
+	hostVeth := fmt.Sprintf("pisp%s", id)
+	clientVeth := fmt.Sprintf("pisc%s", id)
+
+	veth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{Name: hostVeth},
+		PeerName:  clientVeth,
+	}
+	if err := netlink.LinkAdd(veth); err != nil && !strings.Contains(err.Error(), "file exists") {
+		return nil, fmt.Errorf("failed to create veth pair: %v", err)
+	}
+
+	gwNS, err := nsutil.GetFromPath(gatewayNSPath(conf.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gateway netns: %v", err)
+	}
+	defer gwNS.Close()
+
+	hostLink, err := netlink.LinkByName(hostVeth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find host veth %s: %v", hostVeth, err)
+	}
+	if err := netlink.LinkSetNsFd(hostLink, gwNS.Fd()); err != nil {
+		return nil, fmt.Errorf("failed to move %s to gateway namespace: %v", hostVeth, err)
+	}
+
+	brName := bridgeName(conf.Name)
+	if err := gwNS.Do(func(_ nsutil.NetNS) error {
+		link, err := netlink.LinkByName(hostVeth)
+		if err != nil {
+			return fmt.Errorf("failed to find %s in gateway namespace: %v", hostVeth, err)
+		}
+		br, err := netlink.LinkByName(brName)
+		if err != nil {
+			return fmt.Errorf("failed to find bridge %s: %v", brName, err)
+		}
+		if err := netlink.LinkSetMaster(link, br); err != nil {
+			return fmt.Errorf("failed to attach %s to bridge %s: %v", hostVeth, brName, err)
+		}
+		return netlink.LinkSetUp(link)
+	}); err != nil {
+		return nil, err
+	}
+
+	targetNS, err := nsutil.GetFromPath(args.Netns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client netns: %v", err)
+	}
+	defer targetNS.Close()
+
+	clientLink, err := netlink.LinkByName(clientVeth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find client veth %s: %v", clientVeth, err)
+	}
+	if err := netlink.LinkSetNsFd(clientLink, targetNS.Fd()); err != nil {
+		return nil, fmt.Errorf("failed to move client interface to namespace: %v", err)
+	}
+
+	mac, err := configureInterfaceInNS(targetNS, clientVeth, conf.ClientAddress, args.IfName, conf, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setupClientRouting(targetNS, args.IfName, conf.GwAddress, conf); err != nil {
 		return nil, err
 	}
 
 	return mac, nil
 }
 
-func configureInterfaceInNS(ns netns.NsHandle, linkName, address, finalName string) (net.HardwareAddr, error) {
-	// Get current namespace to restore later
-	origns, err := netns.Get()
+// handleClientSRIOV hands the client a VF (virtual function) off
+// conf.PFName instead of a veth peer, for clients that need line-rate
+// networking a veth pair can't provide. If conf.VFIndex is unset, a
+// free VF is picked from the pool; the chosen PF/VF is recorded in the
+// sriov store, keyed by container ID, so cmdDel can release it again.
+func handleClientSRIOV(args *skel.CmdArgs, conf *NetConf) (net.HardwareAddr, error) {
+	if conf.PFName == "" {
+		return nil, fmt.Errorf("sriov mode requires pf_name to be set")
+	}
+
+	vfIndex, err := sriov.Allocate(conf.PFName, conf.VFIndex, args.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+
+	vfName, err := sriov.VFNetdev(conf.PFName, vfIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	targetNS, err := nsutil.GetFromPath(args.Netns)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current netns: %v", err)
+		return nil, fmt.Errorf("failed to get client netns: %v", err)
 	}
-	defer origns.Close()
+	defer targetNS.Close()
 
-	// Switch to target namespace
-	if err := netns.Set(ns); err != nil {
-		return nil, fmt.Errorf("failed to set netns: %v", err)
+	link, err := netlink.LinkByName(vfName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find VF %s: %v", vfName, err)
+	}
+	if err := netlink.LinkSetNsFd(link, targetNS.Fd()); err != nil {
+		return nil, fmt.Errorf("failed to move VF %s to client namespace: %v", vfName, err)
 	}
-	defer netns.Set(origns) // Switch back when done
 
-	// Execute in the target namespace
-	err = func() error {
+	return configureInterfaceInNS(targetNS, vfName, conf.ClientAddress, args.IfName, conf, false)
+}
+
+func configureInterfaceInNS(ns nsutil.NetNS, linkName, address, finalName string, conf *NetConf, isGateway bool) (net.HardwareAddr, error) {
+	var mac net.HardwareAddr
+
+	err := ns.Do(func(_ nsutil.NetNS) error {
 		// Get the link by its current name
 		link, err := netlink.LinkByName(linkName)
 		if err != nil {
@@ -236,62 +564,59 @@ func configureInterfaceInNS(ns netns.NsHandle, linkName, address, finalName stri
 			return fmt.Errorf("failed to bring interface up: %v", err)
 		}
 
-		// Add IP address
-		addr, err := netlink.ParseAddr(address)
-		if err != nil {
-			return fmt.Errorf("failed to parse address %s: %v", address, err)
-		}
+		// When IPAM is delegated, applyIPAMResult assigns the address
+		// instead - skip the static one so IPAM mode doesn't crash on an
+		// empty address or double-configure the interface.
+		if conf.IPAM.Type == "" {
+			addr, err := netlink.ParseAddr(address)
+			if err != nil {
+				return fmt.Errorf("failed to parse address %s: %v", address, err)
+			}
 
-		if err := netlink.AddrAdd(link, addr); err != nil && !strings.Contains(err.Error(), "file exists") {
-			return fmt.Errorf("failed to add address: %v", err)
+			if err := netlink.AddrAdd(link, addr); err != nil && !strings.Contains(err.Error(), "file exists") {
+				return fmt.Errorf("failed to add address: %v", err)
+			}
 		}
 
-		// Disable TX checksums (equivalent to ethtool -K tx off)
-		// Note: This is a simplified approach - in production you might want more sophisticated handling
-		
-		return nil
-	}()
-
-	if err != nil {
-		return nil, err
-	}
-
-	// Get MAC address
-	var mac net.HardwareAddr
-
-	// Switch to target namespace again to get MAC
-	if err := netns.Set(ns); err != nil {
-		return nil, fmt.Errorf("failed to set netns for MAC: %v", err)
-	}
-	defer netns.Set(origns) // Switch back when done
+		sysctls := defaultSysctls(isGateway)
+		for name, value := range conf.Sysctls {
+			if sysctls == nil {
+				sysctls = map[string]string{}
+			}
+			sysctls[name] = value
+		}
+		for name, value := range sysctls {
+			if err := sysctl.Set(name, value); err != nil {
+				return err
+			}
+		}
 
-	err = func() error {
-		link, err := netlink.LinkByName(finalName)
-		if err != nil {
-			return err
+		// Disable TX checksum/TSO offload (equivalent to ethtool -K tx off):
+		// these only help on real NICs and just add overhead on a veth.
+		offloads := defaultOffloads()
+		for name, enabled := range conf.Offloads {
+			offloads[name] = enabled
+		}
+		if err := ethtool.SetFeatures(finalName, offloads); err != nil {
+			return fmt.Errorf("failed to apply offload settings to %s: %v", finalName, err)
 		}
+
 		mac = link.Attrs().HardwareAddr
 		return nil
-	}()
+	})
 
 	return mac, err
 }
 
-func setupClientRouting(ns netns.NsHandle, linkName, gwAddress string) error {
-	// Get current namespace to restore later
-	origns, err := netns.Get()
-	if err != nil {
-		return fmt.Errorf("failed to get current netns: %v", err)
-	}
-	defer origns.Close()
-
-	// Switch to target namespace
-	if err := netns.Set(ns); err != nil {
-		return fmt.Errorf("failed to set netns: %v", err)
+// setupClientRouting adds a default route to gwAddress over linkName. It's a
+// no-op when IPAM is delegated, since the routes in the IPAM result (applied
+// by applyIPAMResult) replace this static one.
+func setupClientRouting(ns nsutil.NetNS, linkName, gwAddress string, conf *NetConf) error {
+	if conf.IPAM.Type != "" {
+		return nil
 	}
-	defer netns.Set(origns) // Switch back when done
 
-	return func() error {
+	return ns.Do(func(_ nsutil.NetNS) error {
 		link, err := netlink.LinkByName(linkName)
 		if err != nil {
 			return fmt.Errorf("failed to find link for routing: %v", err)
@@ -315,9 +640,11 @@ func setupClientRouting(ns netns.NsHandle, linkName, gwAddress string) error {
 		}
 
 		return nil
-	}()
+	})
 }
 
+// cmdDel is idempotent: it must succeed even if called more than once, or
+// after the container netns has already been torn down by the runtime.
 func cmdDel(args *skel.CmdArgs) error {
 	setupLogging()
 	log.Printf("CNI command: DEL")
@@ -327,17 +654,268 @@ func cmdDel(args *skel.CmdArgs) error {
 		return fmt.Errorf("failed to parse network configuration: %v", err)
 	}
 
-	// Remove the network namespace symlinks
+	if conf.IPAM.Type != "" {
+		if err := ipam.ExecDel(conf.IPAM.Type, args.StdinData); err != nil {
+			log.Printf("Warning: IPAM plugin %s failed to release addresses: %v", conf.IPAM.Type, err)
+		}
+	}
+
+	if conf.Mode == "sriov" {
+		if err := releaseSRIOVVF(args); err != nil {
+			return err
+		}
+	} else if conf.Mode == "bridge" && args.IfName != conf.GatewayInterface {
+		if err := deleteBridgeClientVeth(&conf, args); err != nil {
+			return err
+		}
+	} else if args.Netns != "" {
+		if err := deleteVeth(args.Netns, args.IfName); err != nil {
+			return err
+		}
+	}
+
+	if conf.Bandwidth != nil {
+		if err := shaping.Teardown(args.ContainerID); err != nil {
+			log.Printf("Warning: failed to tear down shaping for %s: %v", args.ContainerID, err)
+		}
+	}
+
+	// Remove the network namespace symlinks (idempotent: already-gone is fine)
+	nsPath := filepath.Join("/var/run/netns", args.ContainerID)
+	if err := os.Remove(nsPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to remove netns symlink: %v", err)
+	}
+
 	clientNSPath := filepath.Join("/var/run/netns", fmt.Sprintf("%s-%s-%s", conf.ClientInterface, conf.Name, conf.GatewayInterface))
 	if err := os.Remove(clientNSPath); err != nil && !os.IsNotExist(err) {
 		log.Printf("Warning: failed to remove client netns symlink: %v", err)
 	}
 
+	if conf.Mode == "bridge" && args.IfName == conf.GatewayInterface {
+		// The bridge itself goes away with the gateway's netns; we just
+		// need to stop pointing other clients' ADDs at it.
+		if err := os.Remove(gatewayNSPath(conf.Name)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: failed to remove gateway netns symlink: %v", err)
+		}
+	}
+
 	return nil
 }
 
+// releaseSRIOVVF reverses handleClientSRIOV: it looks up which PF/VF was
+// handed to args.ContainerID, renames the VF back to a stable dev<index>
+// name, and moves it back to the host namespace. It locates the VF by
+// walking sysfs rather than trusting args.IfName, since the container
+// netns (and the name the VF carried inside it) may already be gone by
+// the time DEL runs - in which case the kernel has already auto-migrated
+// the VF back to the host namespace on its own.
+func releaseSRIOVVF(args *skel.CmdArgs) error {
+	alloc, err := sriov.Release(args.ContainerID)
+	if err != nil {
+		return fmt.Errorf("failed to release VF allocation: %v", err)
+	}
+	if alloc == nil {
+		return nil
+	}
+
+	vfName, err := sriov.VFNetdev(alloc.PFName, alloc.VFIndex)
+	if err != nil {
+		return fmt.Errorf("failed to locate VF netdevice: %v", err)
+	}
+	stableName := fmt.Sprintf("dev%d", alloc.VFIndex)
+
+	resetVF := func(hostNS nsutil.NetNS) error {
+		link, err := netlink.LinkByName(vfName)
+		if err != nil {
+			if _, ok := err.(netlink.LinkNotFoundError); ok {
+				return nil
+			}
+			return fmt.Errorf("failed to find VF %s: %v", vfName, err)
+		}
+		if err := netlink.LinkSetDown(link); err != nil {
+			return fmt.Errorf("failed to bring VF %s down: %v", vfName, err)
+		}
+		if err := netlink.LinkSetName(link, stableName); err != nil {
+			return fmt.Errorf("failed to rename VF back to %s: %v", stableName, err)
+		}
+		link, err = netlink.LinkByName(stableName)
+		if err != nil {
+			return fmt.Errorf("failed to find renamed VF %s: %v", stableName, err)
+		}
+		return netlink.LinkSetNsFd(link, hostNS.Fd())
+	}
+
+	if args.Netns != "" {
+		targetNS, err := nsutil.GetFromPath(args.Netns)
+		if err == nil {
+			defer targetNS.Close()
+			return targetNS.Do(resetVF)
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to open netns %s: %v", args.Netns, err)
+		}
+	}
+
+	hostNS, err := nsutil.Current()
+	if err != nil {
+		return fmt.Errorf("failed to get host netns: %v", err)
+	}
+	defer hostNS.Close()
+	return resetVF(hostNS)
+}
+
+// deleteBridgeClientVeth removes a bridge-mode client's host-side veth end.
+// Unlike the per-client path, that end never lives in args.Netns - it's
+// plugged into the bridge inside the gateway's long-lived netns - so it
+// must be looked up there by the same "pisp<id>" name handleClientBridge
+// gave it, using the container ID rather than args.IfName since the
+// client's own netns (and the name its end carries there) may already be
+// gone by the time DEL runs.
+func deleteBridgeClientVeth(conf *NetConf, args *skel.CmdArgs) error {
+	hostVeth := fmt.Sprintf("pisp%s", shortContainerID(args.ContainerID))
+	return deleteVeth(gatewayNSPath(conf.Name), hostVeth)
+}
+
+// deleteVeth removes the linkName link from the given netns. By the time
+// DEL runs, configureInterfaceInNS has already renamed the link to its
+// final name (args.IfName for per-client interfaces, the synthetic
+// "pisp<id>" name for a bridge-mode client's host-side end), so callers
+// must pass that final name rather than the original pre-rename one.
+// Deleting either end of a veth pair removes its peer automatically, so
+// this is sufficient to tear down both the host and container interfaces.
+// Missing namespaces and missing links are treated as already-deleted.
+func deleteVeth(nsPath, linkName string) error {
+	targetNS, err := nsutil.GetFromPath(nsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open netns %s: %v", nsPath, err)
+	}
+	defer targetNS.Close()
+
+	return targetNS.Do(func(_ nsutil.NetNS) error {
+		link, err := netlink.LinkByName(linkName)
+		if err != nil {
+			if _, ok := err.(netlink.LinkNotFoundError); ok {
+				return nil
+			}
+			return fmt.Errorf("failed to find link %s: %v", linkName, err)
+		}
+
+		if err := netlink.LinkDel(link); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete link %s: %v", linkName, err)
+		}
+
+		return nil
+	})
+}
+
+// cmdCheck verifies that the interface created by cmdAdd still exists in
+// the target netns with the expected name, MAC, addresses, and (for
+// clients) default route, per the CNI 1.0 CHECK contract.
 func cmdCheck(args *skel.CmdArgs) error {
-	return fmt.Errorf("CHECK not supported")
+	setupLogging()
+	log.Printf("CNI command: CHECK")
+
+	conf := NetConf{}
+	if err := json.Unmarshal(args.StdinData, &conf); err != nil {
+		return fmt.Errorf("failed to parse network configuration: %v", err)
+	}
+
+	if args.Netns == "" {
+		return types.NewError(types.ErrInvalidNetworkConfig, "netns is gone, nothing to check", "")
+	}
+
+	if conf.IPAM.Type != "" {
+		if err := ipam.ExecCheck(conf.IPAM.Type, args.StdinData); err != nil {
+			return err
+		}
+	}
+
+	if conf.NetConf.RawPrevResult == nil {
+		return types.NewError(types.ErrInvalidNetworkConfig, "missing prevResult, cannot check", "")
+	}
+	if err := version.ParsePrevResult(&conf.NetConf); err != nil {
+		return err
+	}
+	prevResult, err := current.NewResultFromResult(conf.PrevResult)
+	if err != nil {
+		return err
+	}
+
+	linkName := reportedInterfaceName(&conf, args)
+
+	var expectedMac string
+	var expectedIPs []net.IP
+	for _, intf := range prevResult.Interfaces {
+		if intf.Name == linkName && intf.Sandbox == args.Netns {
+			expectedMac = intf.Mac
+		}
+	}
+	for _, ipc := range prevResult.IPs {
+		if ipc.Interface == nil || *ipc.Interface >= len(prevResult.Interfaces) {
+			continue
+		}
+		if prevResult.Interfaces[*ipc.Interface].Name == linkName {
+			expectedIPs = append(expectedIPs, ipc.Address.IP)
+		}
+	}
+
+	targetNS, err := nsutil.GetFromPath(args.Netns)
+	if err != nil {
+		return types.NewError(types.ErrInvalidNetworkConfig, fmt.Sprintf("failed to open netns %s", args.Netns), err.Error())
+	}
+	defer targetNS.Close()
+
+	return targetNS.Do(func(_ nsutil.NetNS) error {
+		link, err := netlink.LinkByName(linkName)
+		if err != nil {
+			return types.NewError(types.ErrInvalidNetworkConfig, fmt.Sprintf("interface %s not found in netns %s", linkName, args.Netns), err.Error())
+		}
+
+		if expectedMac != "" && link.Attrs().HardwareAddr.String() != expectedMac {
+			return types.NewError(types.ErrInvalidNetworkConfig,
+				fmt.Sprintf("interface %s has MAC %s, expected %s", linkName, link.Attrs().HardwareAddr, expectedMac), "")
+		}
+
+		addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
+		if err != nil {
+			return fmt.Errorf("failed to list addresses on %s: %v", linkName, err)
+		}
+		for _, expected := range expectedIPs {
+			found := false
+			for _, addr := range addrs {
+				if addr.IP.Equal(expected) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return types.NewError(types.ErrInvalidNetworkConfig,
+					fmt.Sprintf("expected address %s not found on %s", expected, linkName), "")
+			}
+		}
+
+		if args.IfName != conf.GatewayInterface {
+			routes, err := netlink.RouteList(link, netlink.FAMILY_V4)
+			if err != nil {
+				return fmt.Errorf("failed to list routes on %s: %v", args.IfName, err)
+			}
+			hasDefault := false
+			for _, r := range routes {
+				if r.Dst == nil {
+					hasDefault = true
+					break
+				}
+			}
+			if !hasDefault {
+				return types.NewError(types.ErrInvalidNetworkConfig,
+					fmt.Sprintf("no default route found on %s", args.IfName), "")
+			}
+		}
+
+		return nil
+	})
 }
 
 func getSubnetMaskSize(address string) int {
@@ -0,0 +1,43 @@
+// Package ethtool toggles NIC offload features (tx-checksum, tso, ...)
+// via the SIOCETHTOOL/ETHTOOL_SFEATURES ioctl, equivalent to
+// `ethtool -K <iface> <feature> <on|off>`.
+package ethtool
+
+import (
+	"fmt"
+
+	"github.com/safchain/ethtool"
+)
+
+// SetFeatures toggles the given offload features (keyed by their ethtool
+// feature name, e.g. "tx-checksum-ip-generic") on ifName. Features the
+// driver doesn't expose are silently skipped, since virtual devices like
+// veth don't implement the same feature set as physical NICs.
+func SetFeatures(ifName string, features map[string]bool) error {
+	e, err := ethtool.NewEthtool()
+	if err != nil {
+		return fmt.Errorf("failed to open ethtool handle: %v", err)
+	}
+	defer e.Close()
+
+	supported, err := e.FeatureNames(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to list features on %s: %v", ifName, err)
+	}
+
+	wanted := make(map[string]bool, len(features))
+	for name, enabled := range features {
+		if _, ok := supported[name]; ok {
+			wanted[name] = enabled
+		}
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	if err := e.Change(ifName, wanted); err != nil {
+		return fmt.Errorf("failed to change features on %s: %v", ifName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+// Package nsutil provides a safe way to run code inside a Linux network
+// namespace. It wraps github.com/vishvananda/netns so that callers don't
+// have to hand-roll netns.Set/restore pairs, which are racy with respect
+// to other goroutines and can leave the calling OS thread stuck in the
+// wrong namespace if the restore fails.
+package nsutil
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/vishvananda/netns"
+)
+
+// NetNS is a handle to a network namespace.
+type NetNS struct {
+	handle netns.NsHandle
+}
+
+// GetFromPath opens the network namespace bind-mounted at path.
+func GetFromPath(path string) (NetNS, error) {
+	h, err := netns.GetFromPath(path)
+	if err != nil {
+		return NetNS{}, err
+	}
+	return NetNS{handle: h}, nil
+}
+
+// Current returns a handle to the calling process's current network
+// namespace.
+func Current() (NetNS, error) {
+	h, err := netns.Get()
+	if err != nil {
+		return NetNS{}, err
+	}
+	return NetNS{handle: h}, nil
+}
+
+// Fd returns the file descriptor backing the namespace handle.
+func (n NetNS) Fd() int {
+	return int(n.handle)
+}
+
+// Close releases the namespace handle.
+func (n NetNS) Close() error {
+	return n.handle.Close()
+}
+
+// Do runs toRun with the calling goroutine's OS thread switched into n,
+// passing the namespace the thread was previously in as hostNS. The
+// switch, callback, and restore all happen on a single dedicated OS
+// thread obtained via runtime.LockOSThread, so no other goroutine can
+// ever observe the thread mid-switch. If restoring the original
+// namespace afterwards fails, the thread is left locked (never
+// unlocked) so the Go runtime retires it instead of handing a
+// namespace-poisoned thread back to the scheduler.
+func (n NetNS) Do(toRun func(hostNS NetNS) error) error {
+	hostHandle, err := netns.Get()
+	if err != nil {
+		return fmt.Errorf("failed to get current netns: %v", err)
+	}
+	defer hostHandle.Close()
+	hostNS := NetNS{handle: hostHandle}
+
+	var innerErr error
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		runtime.LockOSThread()
+
+		if err := netns.Set(n.handle); err != nil {
+			innerErr = fmt.Errorf("failed to enter netns: %v", err)
+			runtime.UnlockOSThread()
+			return
+		}
+
+		innerErr = toRun(hostNS)
+
+		if err := netns.Set(hostHandle); err != nil {
+			if innerErr == nil {
+				innerErr = fmt.Errorf("failed to restore original netns: %v", err)
+			}
+			// Leave the thread locked: it's in an unknown namespace now,
+			// so the runtime should scrap it rather than reuse it.
+			return
+		}
+
+		runtime.UnlockOSThread()
+	}()
+	<-done
+
+	return innerErr
+}
@@ -0,0 +1,196 @@
+// Package shaping applies per-client HTB bandwidth limits to the
+// host-side end of a client's veth, the same traffic control a chained
+// containernetworking "bandwidth" meta-plugin would apply, but built in
+// so the pigeon game doesn't need a second plugin in the chain. Egress
+// (client -> host) is redirected to a per-client ifb device before
+// shaping, since a qdisc can only police what leaves a device, not what
+// arrives on it.
+package shaping
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+)
+
+const ifbPrefix = "pisb"
+
+// Limits mirrors the containernetworking bandwidth meta-plugin's
+// "bandwidth" schema: rates in bits/sec, bursts in bits.
+type Limits struct {
+	IngressRate  uint64 `json:"ingressRate"`
+	IngressBurst uint64 `json:"ingressBurst"`
+	EgressRate   uint64 `json:"egressRate"`
+	EgressBurst  uint64 `json:"egressBurst"`
+}
+
+func (l *Limits) isZero() bool {
+	return l == nil || (l.IngressRate == 0 && l.IngressBurst == 0 && l.EgressRate == 0 && l.EgressBurst == 0)
+}
+
+func ifbDeviceName(containerID string) string {
+	id := containerID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return ifbPrefix + id
+}
+
+// Setup shapes hostIfName, the host-side end of a client's veth, per
+// limits. Ingress (host -> client) is throttled directly on
+// hostIfName; egress (client -> host) is redirected to a per-client
+// ifb device that gets its own HTB class.
+func Setup(hostIfName, containerID string, limits *Limits) error {
+	if limits.isZero() {
+		return nil
+	}
+
+	hostLink, err := netlink.LinkByName(hostIfName)
+	if err != nil {
+		return fmt.Errorf("failed to find %s for shaping: %v", hostIfName, err)
+	}
+
+	if limits.IngressRate > 0 && limits.IngressBurst > 0 {
+		if err := addHTBLimit(hostLink.Attrs().Index, limits.IngressRate, limits.IngressBurst); err != nil {
+			return fmt.Errorf("failed to shape ingress on %s: %v", hostIfName, err)
+		}
+	}
+
+	if limits.EgressRate > 0 && limits.EgressBurst > 0 {
+		ifbName := ifbDeviceName(containerID)
+		if err := createIfb(ifbName, hostLink.Attrs().MTU); err != nil {
+			return fmt.Errorf("failed to create %s for shaping: %v", ifbName, err)
+		}
+		ifbLink, err := netlink.LinkByName(ifbName)
+		if err != nil {
+			return fmt.Errorf("failed to find %s after creating it: %v", ifbName, err)
+		}
+
+		if err := redirectToIfb(hostLink, ifbLink); err != nil {
+			return fmt.Errorf("failed to redirect egress on %s to %s: %v", hostIfName, ifbName, err)
+		}
+		if err := addHTBLimit(ifbLink.Attrs().Index, limits.EgressRate, limits.EgressBurst); err != nil {
+			return fmt.Errorf("failed to shape egress on %s: %v", ifbName, err)
+		}
+	}
+
+	return nil
+}
+
+// Teardown removes the ifb device Setup may have created for
+// containerID. Ingress shaping needs no separate teardown: its qdisc
+// lives on the veth itself and goes away when the veth is deleted.
+func Teardown(containerID string) error {
+	name := ifbDeviceName(containerID)
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return nil
+		}
+		return fmt.Errorf("failed to find %s: %v", name, err)
+	}
+	if err := netlink.LinkDel(link); err != nil {
+		return fmt.Errorf("failed to delete %s: %v", name, err)
+	}
+	return nil
+}
+
+func createIfb(name string, mtu int) error {
+	ifb := &netlink.Ifb{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:  name,
+			Flags: net.FlagUp,
+			MTU:   mtu,
+		},
+	}
+	if err := netlink.LinkAdd(ifb); err != nil && !strings.Contains(err.Error(), "file exists") {
+		return err
+	}
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+	return netlink.LinkSetUp(link)
+}
+
+// redirectToIfb adds an ingress qdisc to hostLink and a catch-all u32
+// filter that mirrors every packet arriving on it over to ifbLink,
+// equivalent to `tc filter ... action mirred egress redirect dev <ifb>`.
+func redirectToIfb(hostLink, ifbLink netlink.Link) error {
+	ingress := &netlink.Ingress{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: hostLink.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_INGRESS,
+		},
+	}
+	if err := netlink.QdiscAdd(ingress); err != nil {
+		return fmt.Errorf("add ingress qdisc: %v", err)
+	}
+
+	filter := &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: hostLink.Attrs().Index,
+			Parent:    ingress.QdiscAttrs.Handle,
+			Priority:  1,
+			Protocol:  syscall.ETH_P_ALL,
+		},
+		ClassId:    netlink.MakeHandle(1, 1),
+		RedirIndex: ifbLink.Attrs().Index,
+		Actions: []netlink.Action{
+			&netlink.MirredAction{
+				MirredAction: netlink.TCA_EGRESS_REDIR,
+				Ifindex:      ifbLink.Attrs().Index,
+			},
+		},
+	}
+	return netlink.FilterAdd(filter)
+}
+
+// addHTBLimit attaches an HTB qdisc to linkIndex with a single class
+// rate-limited to rateBps, and a catch-all u32 filter sending all
+// traffic through that class.
+func addHTBLimit(linkIndex int, rateBps, burstBits uint64) error {
+	qdisc := netlink.NewHtb(netlink.QdiscAttrs{
+		LinkIndex: linkIndex,
+		Handle:    netlink.MakeHandle(1, 0),
+		Parent:    netlink.HANDLE_ROOT,
+	})
+	if err := netlink.QdiscAdd(qdisc); err != nil {
+		return fmt.Errorf("add htb qdisc: %v", err)
+	}
+
+	class := netlink.NewHtbClass(
+		netlink.ClassAttrs{
+			LinkIndex: linkIndex,
+			Parent:    netlink.MakeHandle(1, 0),
+			Handle:    netlink.MakeHandle(1, 1),
+		},
+		netlink.HtbClassAttrs{
+			Rate:   rateBps,
+			Ceil:   rateBps,
+			Buffer: uint32(burstBits / 8),
+		},
+	)
+	if err := netlink.ClassAdd(class); err != nil {
+		return fmt.Errorf("add htb class: %v", err)
+	}
+
+	filter := &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: linkIndex,
+			Parent:    netlink.MakeHandle(1, 0),
+			Priority:  1,
+			Protocol:  syscall.ETH_P_ALL,
+		},
+		ClassId: netlink.MakeHandle(1, 1),
+	}
+	if err := netlink.FilterAdd(filter); err != nil {
+		return fmt.Errorf("add htb filter: %v", err)
+	}
+
+	return nil
+}
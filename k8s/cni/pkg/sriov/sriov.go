@@ -0,0 +1,183 @@
+// Package sriov allocates VFs (virtual functions) from a PF (physical
+// function) for clients that need line-rate networking instead of a
+// veth pair, and tracks which VF belongs to which container so cmdDel
+// can find it again later.
+package sriov
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// storePath persists the PF/VF/container mapping across the separate
+// cmdAdd and cmdDel process invocations, the same way the rest of the
+// plugin uses /var/run/netns symlinks to survive across invocations.
+const storePath = "/tmp/reserved_vfs"
+
+// lockPath guards every read-modify-write of storePath with an flock.
+// kubelet can run ADD/DEL for different pods concurrently, so without
+// this two allocations could both read the same free index, or a
+// concurrent release could be lost.
+const lockPath = storePath + ".lock"
+
+// withStoreLock runs fn with an exclusive lock held on lockPath, so the
+// load/modify/save sequence inside fn is atomic across processes.
+func withStoreLock(fn func() error) error {
+	lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open VF store lock %s: %v", lockPath, err)
+	}
+	defer lockFile.Close()
+
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock VF store %s: %v", lockPath, err)
+	}
+	defer syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+
+	return fn()
+}
+
+// Allocation records that vfIndex on pfName has been handed to containerID.
+type Allocation struct {
+	PFName      string `json:"pf_name"`
+	VFIndex     int    `json:"vf_index"`
+	ContainerID string `json:"container_id"`
+}
+
+// NumVFs returns the number of VFs pfName currently has enabled.
+func NumVFs(pfName string) (int, error) {
+	data, err := os.ReadFile(filepath.Join("/sys/class/net", pfName, "device/sriov_numvfs"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read sriov_numvfs for %s: %v", pfName, err)
+	}
+	var n int
+	if _, err := fmt.Sscanf(string(data), "%d", &n); err != nil {
+		return 0, fmt.Errorf("failed to parse sriov_numvfs for %s: %v", pfName, err)
+	}
+	return n, nil
+}
+
+// VFNetdev returns the current netdevice name backing VF vfIndex of
+// pfName, regardless of any renaming a previous ADD did to it. It works
+// by walking the PCI device tree in sysfs rather than trusting a
+// remembered name, so it still finds the VF after its container netns
+// (and whatever name it carried) has been torn down.
+func VFNetdev(pfName string, vfIndex int) (string, error) {
+	netDir := filepath.Join("/sys/class/net", pfName, fmt.Sprintf("device/virtfn%d/net", vfIndex))
+	entries, err := os.ReadDir(netDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to list netdevice for %s virtfn%d: %v", pfName, vfIndex, err)
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("no netdevice found for %s virtfn%d", pfName, vfIndex)
+	}
+	return entries[0].Name(), nil
+}
+
+// Allocate picks a VF of pfName for containerID - explicitIndex if
+// non-nil, otherwise the lowest-numbered VF not already recorded in the
+// store - and records the allocation. Picking the free index and
+// recording it happen under a single store lock, so two concurrent
+// callers can't both be handed the same free index. It is idempotent for
+// a repeat ADD from the same container, but refuses to hand out a VF
+// that's already allocated to someone else.
+func Allocate(pfName string, explicitIndex *int, containerID string) (int, error) {
+	vfIndex := 0
+	err := withStoreLock(func() error {
+		allocs, err := load()
+		if err != nil {
+			return err
+		}
+
+		if explicitIndex != nil {
+			vfIndex = *explicitIndex
+		} else {
+			numVFs, err := NumVFs(pfName)
+			if err != nil {
+				return err
+			}
+			taken := make(map[int]bool, len(allocs))
+			for _, a := range allocs {
+				if a.PFName == pfName {
+					taken[a.VFIndex] = true
+				}
+			}
+			found := false
+			for i := 0; i < numVFs; i++ {
+				if !taken[i] {
+					vfIndex, found = i, true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("no free VFs left on %s (all %d in use)", pfName, numVFs)
+			}
+		}
+
+		for _, a := range allocs {
+			if a.PFName == pfName && a.VFIndex == vfIndex {
+				if a.ContainerID == containerID {
+					return nil
+				}
+				return fmt.Errorf("VF %d on %s is already allocated to container %s", vfIndex, pfName, a.ContainerID)
+			}
+		}
+
+		allocs = append(allocs, Allocation{PFName: pfName, VFIndex: vfIndex, ContainerID: containerID})
+		return save(allocs)
+	})
+	return vfIndex, err
+}
+
+// Release removes and returns the allocation recorded for containerID,
+// or nil if none is recorded (a repeat or out-of-order DEL).
+func Release(containerID string) (*Allocation, error) {
+	var released *Allocation
+	err := withStoreLock(func() error {
+		allocs, err := load()
+		if err != nil {
+			return err
+		}
+
+		for i, a := range allocs {
+			if a.ContainerID == containerID {
+				found := a
+				released = &found
+				allocs = append(allocs[:i], allocs[i+1:]...)
+				return save(allocs)
+			}
+		}
+		return nil
+	})
+	return released, err
+}
+
+func load() ([]Allocation, error) {
+	data, err := os.ReadFile(storePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read VF store %s: %v", storePath, err)
+	}
+
+	var allocs []Allocation
+	if err := json.Unmarshal(data, &allocs); err != nil {
+		return nil, fmt.Errorf("failed to parse VF store %s: %v", storePath, err)
+	}
+	return allocs, nil
+}
+
+func save(allocs []Allocation) error {
+	data, err := json.Marshal(allocs)
+	if err != nil {
+		return fmt.Errorf("failed to encode VF store: %v", err)
+	}
+	if err := os.WriteFile(storePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write VF store %s: %v", storePath, err)
+	}
+	return nil
+}
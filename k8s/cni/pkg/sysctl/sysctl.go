@@ -0,0 +1,22 @@
+// Package sysctl writes /proc/sys knobs scoped to whatever network
+// namespace the calling goroutine's OS thread currently has entered (see
+// nsutil.NetNS.Do), mirroring the per-namespace "kernel knobs" approach
+// libnetwork uses for its sandboxes.
+package sysctl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Set writes value to the /proc/sys entry named by name, where name uses
+// dots as path separators (e.g. "net.ipv4.ip_forward").
+func Set(name, value string) error {
+	path := filepath.Join("/proc/sys", strings.ReplaceAll(name, ".", "/"))
+	if err := os.WriteFile(path, []byte(value), 0o644); err != nil {
+		return fmt.Errorf("failed to set sysctl %s=%s: %v", name, value, err)
+	}
+	return nil
+}